@@ -2,8 +2,10 @@ package zcalendar
 
 import (
 	"bytes"
+	"cmp"
 	"errors"
 	"fmt"
+	"math"
 	"slices"
 	"strconv"
 	"strings"
@@ -11,15 +13,66 @@ import (
 
 // A component is a single unit of the event expression. It represent a
 // potentially repeating value or range in an unspecified time unit.
+//
+// When FromEnd is set, the values are offsets from the end of the unit, 1 being
+// the last value, and the repetition goes toward the end of the unit.
+//
+// When Nearest is set, the component is a single day of the month moved to the
+// nearest weekday, Monday to Friday, without leaving the month.
 type component struct {
-	From   int
-	To     int
-	Repeat int
+	From    int
+	To      int
+	Repeat  int
+	FromEnd bool
+	Nearest bool
+}
+
+// parseNumber parses a decimal number into an integer, scaled by scale. The
+// number can only have a fractional part if scale is greater than 1, in which
+// case it is limited to the precision of the scale.
+func parseNumber(raw string, scale int) (v int, err error) {
+	integer, fraction, found := strings.Cut(raw, ".")
+
+	i, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil {
+		return v, err
+	}
+	v = int(i) * scale
+
+	if !found {
+		return v, nil
+	}
+
+	if fraction == "" {
+		return v, errors.New("empty fractional part")
+	}
+
+	var frac int
+	for _, r := range fraction {
+		scale /= 10
+		if scale == 0 {
+			return v, errors.New("fractional part too precise")
+		}
+		if r < '0' || r > '9' {
+			return v, fmt.Errorf("invalid digit %q in fractional part", r)
+		}
+		frac += int(r-'0') * scale
+	}
+
+	if strings.HasPrefix(integer, "-") {
+		return v - frac, nil
+	}
+	return v + frac, nil
 }
 
 // parseValue create a component from a string representing a simple value with
 // an optional repetition.
 func parseValue(raw string) (c component, err error) {
+	return parseScaledValue(raw, 1)
+}
+
+// parseScaledValue is like parseValue, but the values are scaled by scale.
+func parseScaledValue(raw string, scale int) (c component, err error) {
 	var repeat = ""
 
 	index := strings.Index(raw, "/")
@@ -27,32 +80,49 @@ func parseValue(raw string) (c component, err error) {
 		raw, repeat = raw[:index], raw[index+1:]
 	}
 
-	v, err := strconv.ParseInt(raw, 10, 64)
+	v, err := parseNumber(raw, scale)
 	if err != nil {
-		return c, fmt.Errorf(`invalid value: %w`, err)
+		return c, newParseError(CodeInvalidValue, 0, len(raw), err, "invalid value: %v", err)
 	}
 	if v < 0 {
-		return c, errors.New("invalid negative value")
+		return c, newParseError(CodeNegativeValue, 0, len(raw), nil, "invalid negative value")
 	}
 
-	c.From = int(v)
+	c.From = v
 
-	v, err = strconv.ParseInt(repeat, 10, 64)
-	if index != -1 && err != nil {
-		return c, fmt.Errorf(`invalid repeat: %w`, err)
-	}
-	if v < 0 {
-		return c, errors.New("invalid negative repeat")
+	if index == -1 {
+		return c, nil
 	}
 
-	c.Repeat = int(v)
+	c.Repeat, err = parseRepeat(repeat, scale)
+	if err != nil {
+		return c, wrapParseError(err, index+1, "invalid repeat")
+	}
 
 	return c, nil
 }
 
+// parseRepeat parses the repetition of a value or range.
+func parseRepeat(raw string, scale int) (v int, err error) {
+	v, err = parseNumber(raw, scale)
+	if err != nil {
+		return v, newParseError(CodeInvalidRepeat, 0, len(raw), err, "%v", err)
+	}
+	if v < 0 {
+		return v, newParseError(CodeInvalidRepeat, 0, len(raw), nil, "negative repeat")
+	}
+
+	return v, nil
+}
+
 // parseRange create a component from a string representing a range value with
 // an optional repetition.
 func parseRange(raw string) (c component, err error) {
+	return parseScaledRange(raw, 1)
+}
+
+// parseScaledRange is like parseRange, but the values are scaled by scale.
+func parseScaledRange(raw string, scale int) (c component, err error) {
 	var repeat = ""
 
 	index := strings.Index(raw, "/")
@@ -62,58 +132,83 @@ func parseRange(raw string) (c component, err error) {
 
 	bounds := strings.Split(raw, "..")
 	if len(bounds) != 2 {
-		return c, errors.New("invalid range")
+		return c, newParseError(CodeInvalidRange, 0, len(raw), nil, "invalid range")
 	}
 
-	v, err := strconv.ParseInt(bounds[0], 10, 64)
+	v, err := parseNumber(bounds[0], scale)
 	if err != nil {
-		return c, fmt.Errorf(`invalid value: %w`, err)
+		return c, newParseError(CodeInvalidValue, 0, len(bounds[0]), err, "invalid value: %v", err)
 	}
 	if v < 0 {
-		return c, errors.New("invalid negative lower bound")
+		return c, newParseError(CodeNegativeValue, 0, len(bounds[0]), nil, "invalid negative lower bound")
 	}
-	c.From = int(v)
+	c.From = v
 
-	v, err = strconv.ParseInt(bounds[1], 10, 64)
+	var offset = len(bounds[0]) + len("..")
+
+	v, err = parseNumber(bounds[1], scale)
 	if err != nil {
-		return c, fmt.Errorf(`invalid value: %w`, err)
+		return c, newParseError(CodeInvalidValue, offset, len(bounds[1]), err, "invalid value: %v", err)
 	}
 	if v < 0 {
-		return c, errors.New("invalid negative upper bound")
+		return c, newParseError(CodeNegativeValue, offset, len(bounds[1]), nil, "invalid negative upper bound")
 	}
-	c.To = int(v)
+	c.To = v
 
 	if c.From >= c.To {
-		return c, errors.New("invalid bounds")
+		return c, newParseError(CodeInvalidBounds, 0, len(raw), nil, "invalid bounds")
 	}
 
-	v, err = strconv.ParseInt(repeat, 10, 64)
-	if index != -1 && err != nil {
-		return c, fmt.Errorf(`invalid repeat: %w`, err)
+	if index == -1 {
+		return c, nil
 	}
-	if v < 0 {
-		return c, errors.New("invalid negative repeat")
+
+	c.Repeat, err = parseRepeat(repeat, scale)
+	if err != nil {
+		return c, wrapParseError(err, index+1, "invalid repeat")
 	}
-	c.Repeat = int(v)
 
 	return c, nil
 }
 
+// formatNumber writes a number scaled by scale, with at least width digits
+// for its integer part and as few digits as possible for its fractional part.
+func formatNumber(buf *bytes.Buffer, v, scale, width int) {
+	fmt.Fprintf(buf, "%0*d", width, v/scale)
+
+	if frac := v % scale; frac != 0 {
+		digits := fmt.Sprintf("%0*d", len(strconv.Itoa(scale))-1, frac)
+		buf.WriteString(".")
+		buf.WriteString(strings.TrimRight(digits, "0"))
+	}
+}
+
 // MarshalText implements the encoding.TextMarshaler interface.
 func (c component) MarshalText() (text []byte, err error) {
+	return c.marshalScaled(1), nil
+}
+
+// marshalScaled is like MarshalText, but the values are scaled by scale.
+func (c component) marshalScaled(scale int) []byte {
 	var buf bytes.Buffer
 
-	fmt.Fprintf(&buf, "%02d", c.From)
+	formatNumber(&buf, c.From, scale, 2)
 
 	if c.To != 0 {
-		fmt.Fprintf(&buf, "..%02d", c.To)
+		buf.WriteString("..")
+		formatNumber(&buf, c.To, scale, 2)
 	}
 
 	if c.Repeat != 0 {
-		fmt.Fprintf(&buf, "/%d", c.Repeat)
+		buf.WriteString("/")
+		formatNumber(&buf, c.Repeat, scale, 1)
+	}
+
+	if c.Nearest {
+		buf.WriteString("W")
 	}
 
-	return buf.Bytes(), nil
+	return buf.Bytes()
 }
 
 type components []component
@@ -121,35 +216,89 @@ type components []component
 // parseComponents create a slice of components from a string representing a
 // comma-separated list of values and ranges.
 func parseComponents(raw string) (cs components, err error) {
+	return parseScaledComponents(raw, 1)
+}
+
+// parseScaledComponents is like parseComponents, but the values are scaled by
+// scale.
+func parseScaledComponents(raw string, scale int) (cs components, err error) {
+	return parseBoundedComponents(raw, bounds{min: 0, max: math.MaxInt, scale: scale})
+}
+
+// bounds are the minimum and maximum values of a unit, scaled by scale, and
+// whether its values can be moved to the nearest weekday with a "W" suffix.
+type bounds struct {
+	min     int
+	max     int
+	scale   int
+	nearest bool
+}
+
+// The bounds of each unit of the expressions.
+var (
+	yearsBounds    = bounds{min: MinYears, max: MaxYears, scale: 1}
+	monthsBounds   = bounds{min: 1, max: 12, scale: 1}
+	daysBounds     = bounds{min: 1, max: 31, scale: 1, nearest: true}
+	hoursBounds    = bounds{min: 0, max: 23, scale: 1}
+	minutesBounds  = bounds{min: 0, max: 59, scale: 1}
+	secondsBounds  = bounds{min: 0, max: maxSeconds, scale: microsecondsPerSecond}
+	weeksBounds    = bounds{min: 1, max: 53, scale: 1}
+	yeardaysBounds = bounds{min: 1, max: 366, scale: 1}
+)
+
+// parseBoundedComponents is like parseScaledComponents, but the values must
+// be within the bounds.
+func parseBoundedComponents(raw string, b bounds) (cs components, err error) {
+	var offset int
 	for index, chunk := range strings.Split(raw, ",") {
-		if strings.Contains(chunk, "..") {
-			c, err := parseRange(chunk)
-			if err != nil {
-				return cs, fmt.Errorf(`parsing range %d: %w`, index, err)
-			}
-			cs = append(cs, c)
-			continue
+		var (
+			c       component
+			kind    = "value"
+			value   = chunk
+			nearest = b.nearest && strings.HasSuffix(strings.ToUpper(chunk), "W")
+		)
+
+		if nearest {
+			value = chunk[:len(chunk)-len("W")]
 		}
 
-		c, err := parseValue(chunk)
+		if strings.Contains(value, "..") {
+			c, err = parseScaledRange(value, b.scale)
+			kind = "range"
+		} else {
+			c, err = parseScaledValue(value, b.scale)
+		}
+		if err == nil && (c.From < b.min || c.From > b.max || c.To > b.max) {
+			err = newParseError(CodeOutOfBounds, 0, len(value), nil, "out of bounds %d..%d", b.min/b.scale, b.max/b.scale)
+		}
+		if err == nil && nearest && (c.To != 0 || c.Repeat != 0) {
+			err = newParseError(CodeInvalidValue, 0, len(chunk), nil, "nearest weekday of a %s with several days", kind)
+		}
+		c.Nearest = nearest
 		if err != nil {
-			return cs, fmt.Errorf(`parsing value %d: %w`, index, err)
+			return cs, wrapParseError(err, offset, "parsing %s %d", kind, index)
 		}
+
 		cs = append(cs, c)
+		offset += len(chunk) + len(",")
 	}
 
-	return cs, err
+	return cs, nil
 }
 
 // MarshalText implements the encoding.MarshalText interface for a component
 // slice.
 func (cs components) MarshalText() (text []byte, err error) {
+	return cs.marshalScaled(1), nil
+}
+
+// marshalScaled is like MarshalText, but the values are scaled by scale.
+func (cs components) marshalScaled(scale int) []byte {
 	var parts [][]byte
 	for _, c := range cs {
-		b, _ := c.MarshalText()
-		parts = append(parts, b)
+		parts = append(parts, c.marshalScaled(scale))
 	}
-	return bytes.Join(parts, []byte(",")), nil
+	return bytes.Join(parts, []byte(","))
 }
 
 // String implements the fmt.Stringer interface.
@@ -158,29 +307,324 @@ func (cs components) String() string {
 	return string(b)
 }
 
+// componentsOf creates components from a sorted list of values scaled by scale,
+// grouping the consecutive values as ranges.
+func componentsOf(values []int, scale int) (cs components) {
+	for i := 0; i < len(values); i++ {
+		var c = component{From: values[i]}
+		for i+1 < len(values) && values[i+1] == values[i]+scale {
+			i++
+			c.To = values[i]
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// restrict returns the components restricted to the values between lo and hi,
+// or false if there is none. The components are returned as is if none of
+// their values is out of bounds.
+func (cs components) restrict(lo, hi, max, scale int) (components, bool) {
+	values := cs.scaledValues(max, scale)
+
+	var kept []int
+	for _, v := range values {
+		if v >= lo && v <= hi {
+			kept = append(kept, v)
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil, false
+	}
+
+	if len(kept) == len(values) {
+		return cs, true
+	}
+
+	return componentsOf(kept, scale), true
+}
+
+// maxListedValues is the number of values above which components are not
+// listed to be normalized, as a repeated sub-second value can have millions of
+// them.
+const maxListedValues = 1 << 16
+
+// normalized returns the components matching the same values up to max in
+// their canonical form, all being returned if they match all its values.
+// Components counting from the end of the unit are normalized as offsets.
+// Days moved to the nearest weekday are sorted after the other values.
+// Components with more than maxListedValues values are only sorted and
+// deduplicated.
+func (cs components) normalized(all components, max, scale int) components {
+	if nearest := cs.nearest(); len(nearest) != 0 {
+		var plain = slices.DeleteFunc(slices.Clone(cs), func(c component) bool {
+			return c.Nearest
+		})
+		if len(plain) != 0 && !plain.fromEnd() && plain.isAll(all, max, scale) {
+			return all
+		}
+
+		return append(plain.normalized(all, max, scale), nearest...)
+	}
+
+	if !cs.fromEnd() && cs.isAll(all, max, scale) {
+		return all
+	}
+
+	if cs.count(max, scale) > maxListedValues {
+		var sorted = slices.Clone(cs)
+		slices.SortFunc(sorted, func(a, b component) int {
+			return cmp.Or(a.From-b.From, a.To-b.To, a.Repeat-b.Repeat)
+		})
+		return slices.Compact(sorted)
+	}
+
+	values := cs.scaledValues(max, scale)
+	if len(values) == 0 {
+		return cs
+	}
+
+	if !cs.fromEnd() {
+		return normalizeValues(values, max, scale, true)
+	}
+
+	// Offsets are normalized as the values of the longest unit, which has
+	// a value for each of them, then converted back.
+	var offsets = normalizeValues(values, max, scale, true)
+	for i, c := range offsets {
+		if c.To == 0 {
+			offsets[i] = component{From: max + scale - c.From, Repeat: c.Repeat, FromEnd: true}
+		} else {
+			offsets[i] = component{From: max + scale - c.To, To: max + scale - c.From, Repeat: c.Repeat, FromEnd: true}
+		}
+	}
+
+	slices.SortFunc(offsets, func(a, b component) int {
+		return a.From - b.From
+	})
+
+	return offsets
+}
+
+// normalizeValues creates components from a sorted list of values scaled by
+// scale. Runs of at least 3 consecutive values are written as ranges. Then if
+// repeats are allowed, each longest sequence of the other values with a
+// constant difference is written as a repeat if it has at least 3 values and
+// goes up to max, and the remaining values are listed. The values are only
+// read once, so it takes a linear time.
+func normalizeValues(values []int, max, scale int, repeats bool) (cs components) {
+	var rest []int
+	for i := 0; i < len(values); i++ {
+		var j = i
+		for j+1 < len(values) && values[j+1] == values[j]+scale {
+			j++
+		}
+
+		if j-i >= 2 {
+			cs = append(cs, component{From: values[i], To: values[j]})
+		} else {
+			rest = append(rest, values[i:j+1]...)
+		}
+		i = j
+	}
+
+	for i := 0; i < len(rest); {
+		var j = i + 1
+		if repeats && j < len(rest) {
+			var step = rest[j] - rest[i]
+			for j+1 < len(rest) && rest[j+1]-rest[j] == step {
+				j++
+			}
+
+			if j-i >= 2 && rest[j]+step > max {
+				cs = append(cs, component{From: rest[i], Repeat: step})
+				i = j + 1
+				continue
+			}
+
+			// The values of the sequence but the last can't start
+			// another progression, which would have the same
+			// difference and end, but the last can.
+		}
+
+		for _, v := range rest[i:j] {
+			cs = append(cs, component{From: v})
+		}
+		i = j
+	}
+
+	slices.SortFunc(cs, func(a, b component) int {
+		return a.From - b.From
+	})
+
+	return cs
+}
+
+// isAll returns whether the components have the same values as all, whatever
+// the way they are written. The values of the components are only computed
+// until one of them isn't in all.
+func (cs components) isAll(all components, max, scale int) bool {
+	var lo int
+	for _, want := range all.scaledValues(max, scale) {
+		if v, ok := cs.first(lo, max, scale); !ok || v != want {
+			return false
+		}
+		lo = want + 1
+	}
+
+	_, ok := cs.first(lo, max, scale)
+	return !ok
+}
+
+// hasFraction returns whether some values of the components aren't multiples of
+// the scale.
+func (cs components) hasFraction(max, scale int) bool {
+	for _, c := range cs {
+		if c.Nearest {
+			continue
+		}
+		if c.From%scale != 0 || (c.Repeat%scale != 0 && c.From+c.Repeat <= max) {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the number of values of the components, or more if some of
+// their values are the same or beyond max.
+func (cs components) count(max, scale int) (n int) {
+	for _, c := range cs {
+		var values = 1
+		if c.To > c.From {
+			values = (c.To-c.From)/scale + 1
+		}
+		if c.Repeat != 0 {
+			values *= max/c.Repeat + 1
+		}
+		n += values
+	}
+	return n
+}
+
+// nearest returns the sorted and deduplicated components moved to the nearest
+// weekday.
+func (cs components) nearest() (nearest components) {
+	for _, c := range cs {
+		if c.Nearest && !slices.Contains(nearest, c) {
+			nearest = append(nearest, c)
+		}
+	}
+
+	slices.SortFunc(nearest, func(a, b component) int {
+		return a.From - b.From
+	})
+	return nearest
+}
+
+// nearestDays returns the days of the components moved to the nearest weekday,
+// in a month with the given number of days and whose first day is the weekday
+// first. The days that aren't in the month are skipped.
+func (cs components) nearestDays(days, first int) (values []int) {
+	for _, c := range cs {
+		if !c.Nearest {
+			continue
+		}
+
+		day := c.resolve(days).From
+		if day < 1 || day > days {
+			continue
+		}
+
+		// Saturdays are moved to Friday and Sundays to Monday, unless
+		// this would leave the month.
+		switch (first+day-2)%7 + 1 {
+		case 6:
+			day--
+			if day < 1 {
+				day += 3
+			}
+		case 7:
+			day++
+			if day > days {
+				day -= 3
+			}
+		}
+
+		values = append(values, day)
+	}
+
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+// fromEnd returns whether the components are offsets from the end of the unit.
+func (cs components) fromEnd() bool {
+	for _, c := range cs {
+		if c.FromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve converts a component counting from the end of the unit into a
+// component counting from the start, given the maximum value of the unit.
+func (c component) resolve(max int) component {
+	if !c.FromEnd {
+		return c
+	}
+
+	if c.To == 0 {
+		return component{From: max - c.From + 1, Repeat: c.Repeat}
+	}
+
+	return component{From: max - c.To + 1, To: max - c.From + 1, Repeat: c.Repeat}
+}
+
 // Values return the list of actual values from the various sub-components.
 func (cs components) Values(max int) (values []int) {
+	return cs.scaledValues(max, 1)
+}
+
+// scaledValues is like Values, but the values are scaled by scale. Ranges
+// only contain the multiples of the scale from their lower bound. The days
+// moved to the nearest weekday depend on the month, see nearestDays, so they
+// aren't returned.
+func (cs components) scaledValues(max, scale int) (values []int) {
 	var lenHint int
 	for _, c := range cs {
-		rep := c.Repeat
-		if rep == 0 {
-			rep = 1
+		var count = 1
+		if c.To != 0 {
+			count = (c.To-c.From)/scale + 1
 		}
-		if c.To == 0 {
-			lenHint += rep
-		} else {
-			lenHint += (c.To - c.From + 1) * rep
+		if c.Repeat != 0 {
+			count *= max/c.Repeat + 1
 		}
+		lenHint += count
 	}
 	values = make([]int, 0, lenHint)
 
 	for _, c := range cs {
+		if c.Nearest {
+			continue
+		}
+
+		// Offsets larger than the unit resolve to values before its
+		// start, which must be skipped.
+		var fromEnd = c.FromEnd
+		c = c.resolve(max)
+
 		for {
 			if c.To == 0 {
-				values = append(values, c.From)
+				if (!fromEnd || c.From > 0) && c.From <= max {
+					values = append(values, c.From)
+				}
 			} else {
-				for v := c.From; v <= c.To && v <= max; v++ {
-					values = append(values, v)
+				for v := c.From; v <= c.To && v <= max; v += scale {
+					if !fromEnd || v > 0 {
+						values = append(values, v)
+					}
 				}
 			}
 
@@ -204,22 +648,137 @@ func (cs components) Values(max int) (values []int) {
 	return
 }
 
+// Contains returns whether the value is valid for the components.
+func (cs components) Contains(value, max int) bool {
+	return cs.scaledContains(value, max, 1)
+}
+
+// scaledContains is like Contains, but the values are scaled by scale.
+func (cs components) scaledContains(value, max, scale int) bool {
+	next, ok := cs.first(value, max, scale)
+	return ok && next == value
+}
+
 // Next returns the next valid value for the components, based on the current
 // value. The next value can be equal to the current value if it is valid. The
 // returned value can be smaller than the current value as the values are
 // considered modulo the maximum value.
 func (cs components) Next(current, max int) (next int, diff int, ok bool) {
-	values := cs.Values(max)
-	if len(values) == 0 {
+	return cs.scaledNext(current, max, 1)
+}
+
+// scaledNext is like Next, but the values are scaled by scale.
+func (cs components) scaledNext(current, max, scale int) (next int, diff int, ok bool) {
+	next, ok = cs.first(current, max, scale)
+	if !ok {
+		next, ok = cs.first(0, max, scale)
+	}
+	if !ok {
 		return
 	}
 
-	// Get the first value that is greater or equal to the current value.
-	var i int
-	for i = 0; i < len(values) && values[i] < current; i++ {
+	return next, next - current, true
+}
+
+// Prev returns the previous valid value for the components, based on the
+// current value. The previous value can be equal to the current value if it is
+// valid. The returned value can be greater than the current value as the values
+// are considered modulo the maximum value.
+func (cs components) Prev(current, max int) (prev int, diff int, ok bool) {
+	return cs.scaledPrev(current, max, 1)
+}
+
+// scaledPrev is like Prev, but the values are scaled by scale.
+func (cs components) scaledPrev(current, max, scale int) (prev int, diff int, ok bool) {
+	prev, ok = cs.last(current, max, scale)
+	if !ok {
+		prev, ok = cs.last(max, max, scale)
+	}
+	if !ok {
+		return
 	}
 
-	var val = values[i%len(values)]
+	return prev, prev - current, true
+}
 
-	return val, val - current, true
+// first returns the smallest value of the components, as listed by
+// scaledValues, that is greater or equal to lo. The values are computed rather
+// than listed, as a repeated sub-second value can have millions of them.
+func (cs components) first(lo, max, scale int) (v int, ok bool) {
+	for _, c := range cs {
+		if next, found := c.first(lo, max, scale); found && (!ok || next < v) {
+			v, ok = next, true
+		}
+	}
+	return v, ok
+}
+
+// last returns the largest value of the components, as listed by scaledValues,
+// that is lower or equal to hi.
+func (cs components) last(hi, max, scale int) (v int, ok bool) {
+	for _, c := range cs {
+		if prev, found := c.last(hi, max, scale); found && (!ok || prev > v) {
+			v, ok = prev, true
+		}
+	}
+	return v, ok
+}
+
+// The values of a component are, for each multiple of the scale in its range,
+// an arithmetic progression of its repetition. As a range has at most one
+// value per unit of the scale, each progression is checked in turn.
+
+// first is like components.first for a single component.
+func (c component) first(lo, max, scale int) (v int, ok bool) {
+	if c.Nearest {
+		return
+	}
+
+	// Offsets larger than the unit resolve to values before its start,
+	// which must be skipped.
+	if c.FromEnd && lo < 1 {
+		lo = 1
+	}
+	c = c.resolve(max)
+
+	for base := c.From; base <= c.From || base <= c.To; base += scale {
+		var next = base
+		if next < lo {
+			if c.Repeat == 0 {
+				continue
+			}
+			next += (lo - base + c.Repeat - 1) / c.Repeat * c.Repeat
+		}
+
+		if next <= max && (!ok || next < v) {
+			v, ok = next, true
+		}
+	}
+	return v, ok
+}
+
+// last is like components.last for a single component.
+func (c component) last(hi, max, scale int) (v int, ok bool) {
+	if c.Nearest {
+		return
+	}
+
+	var lo = math.MinInt
+	if c.FromEnd {
+		lo = 1
+	}
+	c = c.resolve(max)
+	hi = min(hi, max)
+
+	for base := c.From; (base <= c.From || base <= c.To) && base <= hi; base += scale {
+		var prev = base
+		if c.Repeat != 0 {
+			prev += (hi - base) / c.Repeat * c.Repeat
+		}
+
+		if prev >= lo && (!ok || prev > v) {
+			v, ok = prev, true
+		}
+	}
+	return v, ok
 }
@@ -19,6 +19,38 @@ func TestParseValue(t *testing.T) {
 	})
 }
 
+func TestParseNumber(t *testing.T) {
+	type Case struct {
+		name  string
+		in    string
+		scale int
+		out   int
+		err   bool
+	}
+
+	for _, c := range []Case{
+		{name: "integer", in: "12", scale: 1, out: 12},
+		{name: "scaled integer", in: "12", scale: 1000, out: 12000},
+		{name: "scaled decimal", in: "1.25", scale: 1000, out: 1250},
+		{name: "negative decimal", in: "-0.5", scale: 1000, out: -500},
+		{name: "unscaled decimal", in: "1.5", scale: 1, err: true},
+		{name: "too precise", in: "1.0001", scale: 1000, err: true},
+		{name: "empty fraction", in: "1.", scale: 1000, err: true},
+		{name: "invalid fraction", in: "1.a", scale: 1000, err: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := parseNumber(c.in, c.scale)
+			if c.err != (err != nil) {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if !c.err && out != c.out {
+				t.Errorf("unexpected output: wanted %v, got %v", c.out, out)
+			}
+		})
+	}
+}
+
 func TestParseRange(t *testing.T) {
 	testParser(t, parseRange, []ParserTestCase{
 		{name: "valid value", in: "1..2", out: component{From: 1, To: 2}},
@@ -61,6 +93,11 @@ func TestComponents_Values(t *testing.T) {
 		{name: "multiple components", comps: components{{From: 1}, {From: 2}}, out: []int{1, 2}},
 		{name: "no duplicates", comps: components{{From: 1, To: 4}, {From: 2, To: 5}}, out: []int{1, 2, 3, 4, 5}},
 		{name: "no component", comps: components{}, out: []int{}},
+		{name: "value above max", comps: components{{From: 1}, {From: 11}}, out: []int{1}},
+		{name: "from end", comps: components{{From: 1, FromEnd: true}}, out: []int{10}},
+		{name: "from end repeat", comps: components{{From: 3, Repeat: 2, FromEnd: true}}, out: []int{8, 10}},
+		{name: "from end range", comps: components{{From: 2, To: 3, FromEnd: true}}, out: []int{8, 9}},
+		{name: "from end out of bounds", comps: components{{From: 12, Repeat: 2, FromEnd: true}}, out: []int{1, 3, 5, 7, 9}},
 	} {
 		t.Run(c.name, func(t *testing.T) {
 			out := c.comps.Values(10)
@@ -71,6 +108,30 @@ func TestComponents_Values(t *testing.T) {
 	}
 }
 
+func TestComponents_Contains(t *testing.T) {
+	type Case struct {
+		name  string
+		comps components
+		value int
+		out   bool
+	}
+
+	// We assume that the maximum value is set to 10 for simplicity's sake.
+	for _, c := range []Case{
+		{name: "single value", comps: components{{From: 1}}, value: 1, out: true},
+		{name: "repeat", comps: components{{From: 1, Repeat: 3}}, value: 7, out: true},
+		{name: "range", comps: components{{From: 1, To: 4}}, value: 5, out: false},
+		{name: "from end", comps: components{{From: 1, FromEnd: true}}, value: 10, out: true},
+		{name: "no component", comps: components{}, value: 1, out: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if out := c.comps.Contains(c.value, 10); out != c.out {
+				t.Errorf("unexpected output: wanted %v, got %v", c.out, out)
+			}
+		})
+	}
+}
+
 func TestComponents_Next(t *testing.T) {
 	type Case struct {
 		name  string
@@ -83,6 +144,9 @@ func TestComponents_Next(t *testing.T) {
 	for _, c := range []Case{
 		{name: "single value", comps: components{{From: 1}}, out: 1, ok: true},
 		{name: "next value", comps: components{{From: 1, To: 9}}, out: 7, ok: true},
+		{name: "repeated value", comps: components{{From: 2, Repeat: 3}}, out: 8, ok: true},
+		{name: "repeated range", comps: components{{From: 0, To: 1, Repeat: 4}}, out: 8, ok: true},
+		{name: "wrapped repeat", comps: components{{From: 1, Repeat: 5}}, out: 1, ok: true},
 		{name: "no value", comps: components{}, out: 0, ok: false},
 	} {
 		t.Run(c.name, func(t *testing.T) {
@@ -98,3 +162,61 @@ func TestComponents_Next(t *testing.T) {
 		})
 	}
 }
+
+func TestComponents_Prev(t *testing.T) {
+	type Case struct {
+		name  string
+		comps components
+		out   int
+		diff  int
+		ok    bool
+	}
+
+	// We assume that the maximum value is set to 10 for simplicity's sake.
+	for _, c := range []Case{
+		{name: "single value", comps: components{{From: 1}}, out: 1, diff: -6, ok: true},
+		{name: "prev value", comps: components{{From: 1, To: 9}}, out: 7, diff: 0, ok: true},
+		{name: "wrapped value", comps: components{{From: 8, To: 9}}, out: 9, diff: 2, ok: true},
+		{name: "repeated value", comps: components{{From: 2, Repeat: 4}}, out: 6, diff: -1, ok: true},
+		{name: "from end", comps: components{{From: 2, To: 3, FromEnd: true}}, out: 9, diff: 2, ok: true},
+		{name: "no value", comps: components{}, out: 0, ok: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, diff, ok := c.comps.Prev(7, 10)
+
+			if ok != c.ok {
+				t.Errorf("unexpected result: wanted %v, got %v", c.ok, ok)
+			}
+
+			if out != c.out {
+				t.Errorf("unexpected output: wanted %v, got %v", c.out, out)
+			}
+
+			if diff != c.diff {
+				t.Errorf("unexpected diff: wanted %v, got %v", c.diff, diff)
+			}
+		})
+	}
+}
+
+func TestComponents_NextSubSecond(t *testing.T) {
+	const max = 60*microsecondsPerSecond - 1
+
+	// The values of a microsecond repeat must be computed, not listed.
+	var cs = components{{From: 0, Repeat: 1}, {From: 500000, To: 2500000, Repeat: 30 * microsecondsPerSecond}}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if next, _, _ := cs.scaledNext(1234567, max, microsecondsPerSecond); next != 1234567 {
+			t.Errorf("unexpected next value: got %d", next)
+		}
+		if prev, _, _ := cs.scaledPrev(max+1, max, microsecondsPerSecond); prev != max {
+			t.Errorf("unexpected prev value: got %d", prev)
+		}
+		if !cs.scaledContains(32500000, max, microsecondsPerSecond) {
+			t.Errorf("unexpected missing value")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations: got %v", allocs)
+	}
+}
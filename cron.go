@@ -0,0 +1,499 @@
+package zcalendar
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros list the special cron specs and their expanded form.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// cronMonths list the names of the months in a cron spec.
+var cronMonths = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// cronWeekdays list the names of the weekdays in a cron spec.
+var cronWeekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// A cronField describes the bounds and names of a field of a cron spec.
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronSecondsField  = cronField{name: "seconds", min: 0, max: 59}
+	cronMinutesField  = cronField{name: "minutes", min: 0, max: 59}
+	cronHoursField    = cronField{name: "hours", min: 0, max: 23}
+	cronDaysField     = cronField{name: "days", min: 1, max: 31}
+	cronMonthsField   = cronField{name: "months", min: 1, max: 12, names: cronMonths}
+	cronWeekdaysField = cronField{name: "weekdays", min: 0, max: 7, names: cronWeekdays}
+)
+
+// A cronTerm is a single comma-separated item of a cron field, representing
+// the values from start to end, every step.
+type cronTerm struct {
+	start int
+	end   int
+	step  int
+}
+
+// ParseCron parses a standard cron spec into an expression. The spec can have
+// 5 fields (minutes, hours, days of month, months and days of week) or 6
+// fields, in which case the first one is the seconds. Fields can contain
+// values, names of months and weekdays, ranges, steps and lists. The macros
+// @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are
+// supported, as well as a CRON_TZ= or TZ= prefix to set the timezone.
+//
+// As cron matches either the day of month or the day of week when both are
+// restricted, which can't be expressed as a single expression, such specs
+// are rejected and must be parsed with ParseCronSchedule. As in Vixie cron,
+// a field starting with "*", such as "*/2", isn't restricted.
+func ParseCron(spec string) (exp Expression, err error) {
+	exp, either, err := parseCron(spec)
+	if err == nil && either {
+		return exp, errors.New("cron specs matching either a day of month or a day of week can't be represented as an expression, use ParseCronSchedule")
+	}
+
+	return exp, err
+}
+
+// MustParseCron is like ParseCron but will panic in case of error.
+func MustParseCron(spec string) (e Expression) {
+	e, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+// ParseCronSchedule is like ParseCron, but returns a schedule, which has two
+// expressions when both the day of month and the day of week are restricted:
+// one for the days of month and one for the days of week.
+func ParseCronSchedule(spec string) (s Schedule, err error) {
+	exp, either, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	if !either {
+		return Schedule{exp}, nil
+	}
+
+	var days, weekdays = exp, exp
+	days.weekdays = defaultWeekdays
+	days.sets = days.compile()
+	weekdays.days = allDays
+	weekdays.sets = weekdays.compile()
+
+	return Schedule{days, weekdays}, nil
+}
+
+// MustParseCronSchedule is like ParseCronSchedule but will panic in case of
+// error.
+func MustParseCronSchedule(spec string) (s Schedule) {
+	s, err := ParseCronSchedule(spec)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// parseCron parses a cron spec into an expression matching both its day of
+// month and its day of week, and returns whether cron matches either of them
+// instead.
+func parseCron(spec string) (exp Expression, either bool, err error) {
+	exp = Expression{
+		weekdays: defaultWeekdays,
+		years:    defaultYears,
+		timezone: defaulttimezone,
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return exp, false, errors.New("empty cron spec")
+	}
+
+	// The timezone is given as a prefix of the spec.
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if !strings.HasPrefix(fields[0], prefix) {
+			continue
+		}
+
+		exp.timezone, err = time.LoadLocation(strings.TrimPrefix(fields[0], prefix))
+		if err != nil {
+			return exp, false, fmt.Errorf("invalid timezone %s", fields[0])
+		}
+		fields = fields[1:]
+		break
+	}
+
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		expanded, ok := cronMacros[strings.ToLower(fields[0])]
+		if !ok {
+			return exp, false, fmt.Errorf("unsupported cron macro %s", fields[0])
+		}
+		fields = strings.Fields(expanded)
+	}
+
+	// Seconds are optional, so add them if missing.
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return exp, false, fmt.Errorf("invalid number of cron fields: expected 5 or 6, got %d", len(fields))
+	}
+
+	exp.seconds, err = parseCronComponents(fields[0], cronSecondsField, allSeconds, microsecondsPerSecond)
+	if err != nil {
+		return exp, false, err
+	}
+
+	exp.minutes, err = parseCronComponents(fields[1], cronMinutesField, allMinutes, 1)
+	if err != nil {
+		return exp, false, err
+	}
+
+	exp.hours, err = parseCronComponents(fields[2], cronHoursField, allHours, 1)
+	if err != nil {
+		return exp, false, err
+	}
+
+	exp.days, err = parseCronComponents(fields[3], cronDaysField, allDays, 1)
+	if err != nil {
+		return exp, false, err
+	}
+
+	exp.months, err = parseCronComponents(fields[4], cronMonthsField, allMonths, 1)
+	if err != nil {
+		return exp, false, err
+	}
+
+	exp.weekdays, err = parseCronWeekdays(fields[5])
+	if err != nil {
+		return exp, false, err
+	}
+
+	exp.sets = exp.compile()
+
+	return exp, !isCronStar(fields[3]) && !isCronStar(fields[5]), nil
+}
+
+// isCronStar returns whether the day of month or day of week field starts
+// with a "*" or is a "?", in which case cron matches both of them rather than
+// either of them, as in Vixie cron.
+func isCronStar(raw string) bool {
+	return strings.HasPrefix(raw, "*") || raw == "?"
+}
+
+// isCronWildcard returns whether the cron field matches any value.
+func isCronWildcard(raw string) bool {
+	return raw == "*" || raw == "?"
+}
+
+// parseCronValue parses a single value of a cron field, which can be a number
+// or a name.
+func parseCronValue(raw string, field cronField) (v int, err error) {
+	if v, ok := field.names[strings.ToLower(raw)]; ok {
+		return v, nil
+	}
+
+	i, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return v, fmt.Errorf(`invalid value: %w`, err)
+	}
+	v = int(i)
+
+	if v < field.min || v > field.max {
+		return v, fmt.Errorf("value %d out of bounds %d..%d", v, field.min, field.max)
+	}
+
+	return v, nil
+}
+
+// parseCronTerms parses a cron field into a list of terms.
+func parseCronTerms(raw string, field cronField) (terms []cronTerm, err error) {
+	for index, chunk := range strings.Split(raw, ",") {
+		var term = cronTerm{start: field.min, end: field.max, step: 1}
+
+		rng, step, found := strings.Cut(chunk, "/")
+		if found {
+			v, err := strconv.ParseInt(step, 10, 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("parsing %s: invalid step in term %d", field.name, index)
+			}
+			term.step = int(v)
+		}
+
+		if !isCronWildcard(rng) {
+			from, to, isRange := strings.Cut(rng, "-")
+
+			term.start, err = parseCronValue(from, field)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: term %d: %w", field.name, index, err)
+			}
+
+			// A single value with a step, such as 5/15, goes up to
+			// the maximum of the field.
+			switch {
+			case isRange:
+				term.end, err = parseCronValue(to, field)
+				if err != nil {
+					return nil, fmt.Errorf("parsing %s: term %d: %w", field.name, index, err)
+				}
+			case !found:
+				term.end = term.start
+			}
+
+			if term.start > term.end {
+				return nil, fmt.Errorf("parsing %s: term %d: invalid bounds", field.name, index)
+			}
+		}
+
+		terms = append(terms, term)
+	}
+
+	return terms, nil
+}
+
+// parseCronComponents parses a cron field into components, the values being
+// scaled by scale. Wildcards are converted into all.
+func parseCronComponents(raw string, field cronField, all components, scale int) (cs components, err error) {
+	if isCronWildcard(raw) {
+		return all, nil
+	}
+
+	terms, err := parseCronTerms(raw, field)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range terms {
+		switch {
+		case t.step == 1 && t.start == t.end:
+			cs = append(cs, component{From: t.start * scale})
+		case t.step == 1:
+			cs = append(cs, component{From: t.start * scale, To: t.end * scale})
+		case t.end == field.max:
+			// A repeated value goes up to the maximum of the unit.
+			cs = append(cs, component{From: t.start * scale, Repeat: t.step * scale})
+		default:
+			for v := t.start; v <= t.end; v += t.step {
+				cs = append(cs, component{From: v * scale})
+			}
+		}
+	}
+
+	return cs, nil
+}
+
+// parseCronWeekdays parses a cron day of week field into weekday components.
+func parseCronWeekdays(raw string) (cs weekdayComponents, err error) {
+	if isCronWildcard(raw) {
+		return allWeekdays, nil
+	}
+
+	terms, err := parseCronTerms(raw, cronWeekdaysField)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cron weekdays go from Sunday=0 to Saturday=6, with Sunday=7 also
+	// allowed, while our weekdays are Monday=1..Sunday=7.
+	var days [8]bool
+	for _, t := range terms {
+		for v := t.start; v <= t.end; v += t.step {
+			days[(v+6)%7+1] = true
+		}
+	}
+
+	// Group consecutive days as ranges.
+	for day := 1; day <= 7; day++ {
+		if !days[day] {
+			continue
+		}
+
+		var c = weekdayComponent{From: day}
+		for day < 7 && days[day+1] {
+			day++
+			c.To = day
+		}
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+// A CronError is returned when an expression uses a feature that can't be
+// represented in a cron spec.
+type CronError struct {
+	Feature string
+}
+
+// Error implements the error interface.
+func (e *CronError) Error() string {
+	return fmt.Sprintf("expression can't be represented as cron: %s", e.Feature)
+}
+
+// Cron returns the cron spec equivalent to the expression. The spec has 5
+// fields, or 6 if the expression isn't matching only the first second of the
+// minutes. A *CronError is returned if the expression is an exclusion,
+// restricts the years, isn't in UTC, restricts both the weekdays and the days
+// of the month, counts the days from the end of the month, moves days to the
+// nearest weekday, restricts the weekdays to some of their occurrences,
+// restricts the ISO weeks or the days of the year or has sub-second values.
+func (e Expression) Cron() (spec string, err error) {
+	if e.excluded {
+		return "", &CronError{Feature: "exclusion"}
+	}
+
+	if len(e.years.Values(MaxYears)) != MaxYears-MinYears+1 {
+		return "", &CronError{Feature: "year restriction"}
+	}
+
+	if e.timezone == nil || e.timezone.String() != "UTC" {
+		return "", &CronError{Feature: "non-UTC timezone"}
+	}
+
+	if e.days.fromEnd() {
+		return "", &CronError{Feature: "end of month"}
+	}
+
+	if e.hasNearest() {
+		return "", &CronError{Feature: "nearest weekday"}
+	}
+
+	if isRestricted(e.weeks, allWeeks, 53) {
+		return "", &CronError{Feature: "ISO week"}
+	}
+
+	if isRestricted(e.yeardays, allYeardays, 366) {
+		return "", &CronError{Feature: "day of year"}
+	}
+
+	if e.weekdays.hasNth() {
+		return "", &CronError{Feature: "weekday occurrence"}
+	}
+
+	var (
+		restrictedDays     = len(e.days.Values(31)) != 31
+		restrictedWeekdays = !e.weekdays.isAll()
+	)
+	if restrictedDays && restrictedWeekdays {
+		return "", &CronError{Feature: "weekday and day of month"}
+	}
+
+	if e.seconds.hasFraction(maxSeconds, microsecondsPerSecond) {
+		return "", &CronError{Feature: "sub-second"}
+	}
+
+	var seconds = make(components, len(e.seconds))
+	for i, c := range e.seconds {
+		seconds[i] = component{From: c.From / microsecondsPerSecond, To: c.To / microsecondsPerSecond, Repeat: c.Repeat / microsecondsPerSecond}
+	}
+
+	var fields = []string{
+		formatCronComponents(e.minutes, cronMinutesField),
+		formatCronComponents(e.hours, cronHoursField),
+		formatCronComponents(e.days, cronDaysField),
+		formatCronComponents(e.months, cronMonthsField),
+		formatCronWeekdays(e.weekdays),
+	}
+
+	if !slices.Equal(seconds.Values(59), []int{0}) {
+		fields = append([]string{formatCronComponents(seconds, cronSecondsField)}, fields...)
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// formatCronComponents formats the components as a cron field.
+func formatCronComponents(cs components, field cronField) string {
+	values := cs.Values(field.max)
+	if len(values) == field.max-field.min+1 {
+		return "*"
+	}
+
+	var terms []string
+	for _, c := range cs {
+		switch {
+		case c.To == 0 && c.Repeat == 0:
+			terms = append(terms, strconv.Itoa(c.From))
+		case c.Repeat == 0:
+			terms = append(terms, fmt.Sprintf("%d-%d", c.From, c.To))
+		case c.To == 0 && c.From == field.min:
+			terms = append(terms, fmt.Sprintf("*/%d", c.Repeat))
+		case c.To == 0:
+			terms = append(terms, fmt.Sprintf("%d-%d/%d", c.From, field.max, c.Repeat))
+		default:
+			terms = append(terms, formatCronRuns(components{c}.Values(field.max)))
+		}
+	}
+
+	return strings.Join(terms, ",")
+}
+
+// formatCronWeekdays formats the weekday components as a cron field.
+func formatCronWeekdays(cs weekdayComponents) string {
+	if cs.isAll() {
+		return "*"
+	}
+
+	var terms []string
+	for _, c := range cs {
+		switch {
+		case c.Repeat != 0:
+			// Steps of weekdays don't end at the same weekday in all
+			// the cron implementations, so list the values instead.
+			terms = append(terms, formatCronRuns(c.days()))
+		case c.To == 0:
+			terms = append(terms, strconv.Itoa(c.From))
+		default:
+			terms = append(terms, fmt.Sprintf("%d-%d", c.From, c.To))
+		}
+	}
+
+	return strings.Join(terms, ",")
+}
+
+// formatCronRuns formats a sorted list of values as a cron list, grouping the
+// consecutive values as ranges. Repeated ranges don't have the same meaning in
+// cron and calendar expressions, so their values are listed instead, both when
+// parsing and formatting.
+func formatCronRuns(values []int) string {
+	var terms []string
+	for i := 0; i < len(values); i++ {
+		var start = values[i]
+		for i+1 < len(values) && values[i+1] == values[i]+1 {
+			i++
+		}
+
+		if values[i] == start {
+			terms = append(terms, strconv.Itoa(start))
+			continue
+		}
+		terms = append(terms, fmt.Sprintf("%d-%d", start, values[i]))
+	}
+
+	return strings.Join(terms, ",")
+}
@@ -0,0 +1,178 @@
+package zcalendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	type Case struct {
+		name string
+		in   string
+		out  string
+		err  bool
+	}
+
+	for _, c := range []Case{
+		{name: "every minute", in: "* * * * *", out: "*-*-* *:*:00"},
+		{name: "fixed time", in: "30 8 * * *", out: "*-*-* 08:30:00"},
+		{name: "seconds", in: "15 30 8 * * *", out: "*-*-* 08:30:15"},
+		{name: "steps", in: "*/15 */2 * * *", out: "*-*-* 00/2:00/15:00"},
+		{name: "step from value", in: "5/20 * * * *", out: "*-*-* *:05/20:00"},
+		{name: "bounded step", in: "0 9-17/4 * * *", out: "*-*-* 09,13,17:00:00"},
+		{name: "ranges and lists", in: "0 9-17 1,15 * *", out: "*-*-01,15 09..17:00:00"},
+		{name: "month names", in: "0 0 1 JAN,jul *", out: "*-01,07-01 00:00:00"},
+		{name: "weekday names", in: "0 0 * * MON-FRI", out: "Mon..Fri *-*-* 00:00:00"},
+		{name: "sunday as zero", in: "0 0 * * 0,6", out: "Sat,Sun *-*-* 00:00:00"},
+		{name: "sunday as seven", in: "0 0 * * 5-7", out: "Fri..Sun *-*-* 00:00:00"},
+		{name: "weekday step", in: "0 0 * * */2", out: "Tue/2,Sun *-*-* 00:00:00"},
+		{name: "question mark", in: "0 0 ? * MON", out: "Mon *-*-* 00:00:00"},
+		{name: "macro", in: "@daily", out: "*-*-* 00:00:00"},
+		{name: "weekly macro", in: "@weekly", out: "Sun *-*-* 00:00:00"},
+		{name: "timezone", in: "CRON_TZ=Europe/Paris 0 12 * * *", out: "*-*-* 12:00:00 Europe/Paris"},
+		{name: "timezone macro", in: "TZ=UTC @hourly", out: "*-*-* *:00:00 UTC"},
+		{name: "empty spec", in: "", err: true},
+		{name: "too few fields", in: "* * * *", err: true},
+		{name: "too many fields", in: "* * * * * * *", err: true},
+		{name: "out of bounds", in: "0 24 * * *", err: true},
+		{name: "invalid bounds", in: "0 17-9 * * *", err: true},
+		{name: "invalid step", in: "*/0 * * * *", err: true},
+		{name: "invalid name", in: "0 0 * FOO *", err: true},
+		{name: "unsupported macro", in: "@reboot", err: true},
+		{name: "invalid timezone", in: "TZ=Nowhere * * * * *", err: true},
+		{name: "day of month or week", in: "0 0 1 * MON", err: true},
+		{name: "day of month step and week", in: "0 0 */2 * MON", out: "Mon *-*-01/2 00:00:00"},
+		{name: "day of month and week step", in: "0 0 1 * */2", out: "Tue/2,Sun *-*-01 00:00:00"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := ParseCron(c.in)
+			if c.err != (err != nil) {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if !c.err && out.String() != c.out {
+				t.Errorf("unexpected output: wanted %s, got %s", c.out, out.String())
+			}
+		})
+	}
+}
+
+func TestParseCronSchedule(t *testing.T) {
+	type Case struct {
+		name string
+		in   string
+		out  string
+		err  bool
+	}
+
+	for _, c := range []Case{
+		{name: "single expression", in: "0 0 1 * *", out: "*-*-01 00:00:00 UTC"},
+		{name: "day of month or week", in: "0 0 1,15 * MON", out: "*-*-01,15 00:00:00 UTC\nMon *-*-* 00:00:00 UTC"},
+		{name: "day of month step and week", in: "0 0 */2 * MON", out: "Mon *-*-01/2 00:00:00 UTC"},
+		{name: "invalid spec", in: "0 0 32 * MON", err: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := ParseCronSchedule("TZ=UTC " + c.in)
+			if c.err != (err != nil) {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+			if c.err {
+				return
+			}
+
+			text, err := out.MarshalText()
+			if err != nil || string(text) != c.out {
+				t.Errorf("unexpected output: wanted %q, got %q, %v", c.out, text, err)
+			}
+		})
+	}
+
+	// Cron matches the 1st of the month, or any Monday.
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+	next, ok := MustParseCronSchedule("TZ=UTC 0 0 1 * MON").Next(current)
+	if !ok || !next.Equal(time.Date(2006, 01, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next: got %v, %v", next, ok)
+	}
+}
+
+func TestParseCron_Next(t *testing.T) {
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	exp := MustParseCron("TZ=UTC 0 9-17/4 * * MON-FRI")
+
+	next, ok := exp.Next(current)
+	if !ok || !next.Equal(time.Date(2006, 01, 02, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next: got %v, %v", next, ok)
+	}
+}
+
+func TestExpression_Cron(t *testing.T) {
+	type Case struct {
+		name    string
+		in      string
+		out     string
+		feature string
+	}
+
+	for _, c := range []Case{
+		{name: "daily", in: "daily UTC", out: "0 0 * * *"},
+		{name: "every minute", in: "*-*-* *:* UTC", out: "* * * * *"},
+		{name: "seconds", in: "*-*-* 08:30:15 UTC", out: "15 30 8 * * *"},
+		{name: "every second", in: "*:*:* UTC", out: "* * * * * *"},
+		{name: "repeats", in: "*-*-* 00/2,05/6:05/20 UTC", out: "5-59/20 */2,5-23/6 * * *"},
+		{name: "ranges and lists", in: "*-01,07-01..15 09..17:00 UTC", out: "0 9-17 1-15 1,7 *"},
+		{name: "repeated range", in: "*-*-* 00..01/12:00 UTC", out: "0 0-1,12-13 * * *"},
+		{name: "weekdays", in: "Mon..Fri,Sun 12:00 UTC", out: "0 12 * * 1-5,7"},
+		{name: "repeated weekdays", in: "Mon/2 12:00 UTC", out: "0 12 * * 1,3,5,7"},
+		{name: "years", in: "2006-*-* UTC", feature: "year restriction"},
+		{name: "timezone", in: "*-*-* 00:00 Europe/Paris", feature: "non-UTC timezone"},
+		{name: "weekday and day of month", in: "Mon *-*-01 UTC", feature: "weekday and day of month"},
+		{name: "end of month", in: "*-*~01 UTC", feature: "end of month"},
+		{name: "weekday occurrence", in: "Tue#2 12:00 UTC", feature: "weekday occurrence"},
+		{name: "nearest weekday", in: "*-*-15W UTC", feature: "nearest weekday"},
+		{name: "iso week", in: "W02 UTC", feature: "ISO week"},
+		{name: "day of year", in: "D1 UTC", feature: "day of year"},
+		{name: "sub-second", in: "*:*:0/0.5 UTC", feature: "sub-second"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := MustParse(c.in).Cron()
+
+			var cronErr *CronError
+			if c.feature != "" {
+				if !errors.As(err, &cronErr) || cronErr.Feature != c.feature {
+					t.Fatalf("unexpected error: wanted feature %s, got %v", c.feature, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if out != c.out {
+				t.Errorf("unexpected output: wanted %s, got %s", c.out, out)
+			}
+		})
+	}
+}
+
+func TestExpression_Cron_RoundTrip(t *testing.T) {
+	for _, spec := range []string{
+		"0 0 * * *",
+		"*/15 9-17 * * 1-5",
+		"30 2 1,15 * *",
+		"0 0 1 1 *",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			out, err := MustParseCron("TZ=UTC " + spec).Cron()
+			if err != nil {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if out != spec {
+				t.Errorf("unexpected output: wanted %s, got %s", spec, out)
+			}
+		})
+	}
+}
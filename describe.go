@@ -0,0 +1,530 @@
+package zcalendar
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A timeUnit describes a unit of the time of day for the descriptions.
+type timeUnit struct {
+	singular string
+	plural   string
+	size     int // The number of values of the unit.
+	scale    int
+}
+
+// timeUnits list the units of the time of day, from the biggest to the
+// smallest. Their names are used in the keys of the locales' phrases.
+var timeUnits = [3]timeUnit{
+	{singular: "hour", plural: "hours", size: 24, scale: 1},
+	{singular: "minute", plural: "minutes", size: 60, scale: 1},
+	{singular: "second", plural: "seconds", size: 60, scale: microsecondsPerSecond},
+}
+
+// Describe returns a description of the expression in the language of the
+// locale, such as "every 15 minutes from 08:00 to 18:45 on odd days of the
+// month, Monday to Friday, Paris time" in English. The normalized expression
+// is described, so equal expressions have the same description.
+func (e Expression) Describe(l Locale) string {
+	e = e.Normalize()
+
+	var (
+		parts    = []string{e.describeTime(l)}
+		weekdays = e.weekdays
+		months   = e.months.Values(12)
+		merged   bool
+	)
+
+	if len(e.days.Values(31)) != 31 || e.days.fromEnd() {
+		// Plain lists of months read better as the end of the days.
+		var days string
+		if len(months) != 12 && !hasRepeat(e.months) {
+			days = describeDays(l, e.days, describeMonths(l, e.months))
+			merged = true
+		} else {
+			days = describeDays(l, e.days, "")
+		}
+
+		if !weekdays.isAll() {
+			days = phrase(l, "days and weekdays", days, describeWeekdays(l, weekdays))
+		}
+
+		parts = append(parts, days)
+	} else if !weekdays.isAll() {
+		// The article of the plain weekdays may differ from the one of
+		// their occurrences.
+		var key = "on weekdays"
+		if len(weekdays.Values()) == 0 {
+			key = "on nth weekdays"
+		}
+		parts = append(parts, phrase(l, key, describeWeekdays(l, weekdays)))
+	}
+
+	if len(months) != 12 && !merged {
+		parts = append(parts, phrase(l, "in months", describeMonths(l, e.months)))
+	}
+
+	if len(e.years.Values(MaxYears)) != MaxYears-MinYears+1 {
+		parts = append(parts, phrase(l, "in years", describeNumbers(l, e.years, "year")))
+	}
+
+	if isRestricted(e.weeks, allWeeks, 53) {
+		var key = "in weeks"
+		if len(e.weeks.Values(53)) == 1 {
+			key = "in week"
+		}
+		parts = append(parts, phrase(l, key, describeNumbers(l, e.weeks, "week")))
+	}
+
+	if isRestricted(e.yeardays, allYeardays, 366) {
+		var key = "on days of the year"
+		if len(e.yeardays.Values(366)) == 1 {
+			key = "on day of the year"
+		}
+		parts = append(parts, phrase(l, key, describeNumbers(l, e.yeardays, "yearday")))
+	}
+
+	var description = strings.Join(parts, " ")
+	if e.timezone == time.Local {
+		return description
+	}
+
+	// Use the city of the location if there is one.
+	var name = e.timezone.String()
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		return phrase(l, "city time", description, strings.ReplaceAll(name[i+1:], "_", " "))
+	}
+
+	return phrase(l, "zone time", description, name)
+}
+
+// Describe returns a description of the schedule in the language of the
+// locale, made of the descriptions of its expressions followed by those of its
+// exclusions.
+func (s Schedule) Describe(l Locale) string {
+	var included, excluded []string
+	for _, exp := range s {
+		if exp.excluded {
+			excluded = append(excluded, exp.Describe(l))
+		} else {
+			included = append(included, exp.Describe(l))
+		}
+	}
+
+	var description = strings.Join(included, "; ")
+	if len(excluded) == 0 {
+		return description
+	}
+	return phrase(l, "except", description, strings.Join(excluded, "; "))
+}
+
+// timeValues holds the values of a unit of the time of day. Their count, bounds
+// and step are computed from the list of values, or from the components if
+// they have more than maxListedValues values, as a repeated sub-second value
+// can have millions of them.
+type timeValues struct {
+	list        []int
+	count       int
+	first, last int
+	step        int
+	runs        components // The values grouped as ranges, or the components.
+}
+
+// listedTimeValues returns the time values of a sorted list of values scaled by
+// scale.
+func listedTimeValues(values []int, scale int) timeValues {
+	return timeValues{
+		list:  values,
+		count: len(values),
+		first: values[0],
+		last:  values[len(values)-1],
+		step:  stepOf(values),
+		runs:  componentsOf(values, scale),
+	}
+}
+
+// secondsTimeValues returns the time values of the normalized seconds.
+func secondsTimeValues(cs components) timeValues {
+	if cs.count(maxSeconds, microsecondsPerSecond) <= maxListedValues {
+		return listedTimeValues(cs.scaledValues(maxSeconds, microsecondsPerSecond), microsecondsPerSecond)
+	}
+
+	var v = timeValues{count: cs.count(maxSeconds, microsecondsPerSecond), runs: cs}
+	v.first, _ = cs.first(0, maxSeconds, microsecondsPerSecond)
+	v.last, _ = cs.last(maxSeconds, maxSeconds, microsecondsPerSecond)
+	if len(cs) == 1 && cs[0].To == 0 {
+		v.step = cs[0].Repeat
+	}
+	return v
+}
+
+// describeTime returns the description of the time of day of the expression.
+func (e Expression) describeTime(l Locale) string {
+	var values = [3]timeValues{
+		listedTimeValues(e.hours.Values(23), 1),
+		listedTimeValues(e.minutes.Values(59), 1),
+		secondsTimeValues(e.seconds),
+	}
+
+	var withSeconds = values[2].last != 0
+
+	// A few points in time are simply listed.
+	if values[0].count*values[1].count*values[2].count <= 3 {
+		var times []string
+		for _, h := range values[0].list {
+			for _, m := range values[1].list {
+				for _, s := range values[2].list {
+					times = append(times, formatTime(h, m, s, withSeconds))
+				}
+			}
+		}
+		return phrase(l, "at", joinWords(l, times))
+	}
+
+	// Find the smallest unit having several values, which gives the
+	// frequency of the expression.
+	var f = len(values) - 1
+	for values[f].count == 1 {
+		f--
+	}
+
+	var (
+		unit      = timeUnits[f]
+		first     = formatTime(values[0].first, values[1].first, values[2].first, withSeconds)
+		last      = formatTime(values[0].last, values[1].last, values[2].last, withSeconds)
+		step      = values[f].step
+		wraps     = step != 0 && values[f].first < step && values[f].last == unit.size*unit.scale-step+values[f].first
+		coarser   = true // Whether all the bigger units are full.
+		varying   = -1   // The biggest unit having several values.
+		contained = true // Whether all the bigger units are ranges.
+	)
+
+	for k := 0; k < f; k++ {
+		if values[k].count != timeUnits[k].size {
+			coarser = false
+		}
+		if values[k].count > 1 && varying == -1 {
+			varying = k
+		}
+		if values[k].step != timeUnits[k].scale && values[k].count > 1 {
+			contained = false
+		}
+	}
+
+	// The values are a progression of points in time if the bigger units
+	// between the frequency and the biggest varying unit are full.
+	var progression = step != 0 && contained
+	if varying != -1 {
+		progression = progression && wraps
+		for k := varying + 1; k < f; k++ {
+			progression = progression && values[k].count == timeUnits[k].size
+		}
+	}
+
+	var every = l.Phrase("every " + unit.singular)
+	if step != unit.scale {
+		every = phrase(l, "every "+unit.plural, formatScaled(step, unit.scale))
+	}
+
+	if progression {
+		if !coarser || !wraps {
+			return every + " " + phrase(l, "from", first, last)
+		}
+
+		if first != formatTime(0, 0, 0, withSeconds) {
+			return every + " " + phrase(l, "starting at", first)
+		}
+
+		return every
+	}
+
+	// Otherwise, each unit is described separately.
+	var parts []string
+	if wraps && values[f].first == 0 {
+		parts = append(parts, every)
+	} else {
+		parts = append(parts, describeTimeUnit(l, "at", f, values[f]))
+	}
+
+	for k := f - 1; k >= 0; k-- {
+		if values[k].count != timeUnits[k].size {
+			parts = append(parts, describeTimeUnit(l, "during", k, values[k]))
+		}
+	}
+
+	for k := f + 1; k < len(values); k++ {
+		if values[k].first != 0 {
+			parts = append(parts, describeTimeUnit(l, "at", k, values[k]))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// describeTimeUnit returns the description of the values of a unit of the
+// time of day, such as "at minutes 0, 20 and 40 to 45". The kind of the
+// description is either "at" or "during".
+func describeTimeUnit(l Locale, kind string, k int, values timeValues) string {
+	var unit = timeUnits[k]
+
+	var items []string
+	for _, c := range values.runs {
+		var item = formatScaled(c.From, unit.scale)
+		if c.To != 0 {
+			item = phrase(l, "range", item, formatScaled(c.To, unit.scale))
+		}
+
+		// Only the seconds that aren't listed have repeated components.
+		if c.Repeat != 0 {
+			item = phrase(l, "repeated seconds", item, formatScaled(c.Repeat, unit.scale))
+		}
+
+		items = append(items, item)
+	}
+
+	if values.count == 1 {
+		return phrase(l, kind+" "+unit.singular, joinWords(l, items))
+	}
+	return phrase(l, kind+" "+unit.plural, joinWords(l, items))
+}
+
+// describeDays returns the description of the days of the month, followed by
+// the months if there are any, such as "on the 1st and the 15th of the month"
+// in English.
+func describeDays(l Locale, cs components, months string) string {
+	var suffix string
+	if months != "" {
+		suffix = " of months"
+	}
+
+	if len(cs) == 1 && !cs[0].FromEnd && cs[0].To == 0 && cs[0].Repeat == 2 && cs[0].From <= 2 {
+		var key = "on odd days"
+		if cs[0].From == 2 {
+			key = "on even days"
+		}
+
+		if months == "" {
+			return l.Phrase(key)
+		}
+		return phrase(l, key+suffix, months)
+	}
+
+	var items []string
+	for _, c := range cs {
+		var item string
+		switch {
+		case c.FromEnd && c.To == 0:
+			item = describeDayFromEnd(l, c.From)
+		case c.FromEnd && c.From == 1:
+			item = phrase(l, "last days", c.To)
+		case c.FromEnd:
+			item = phrase(l, "range", describeDayFromEnd(l, c.To), describeDayFromEnd(l, c.From))
+		case c.To == 0:
+			item = phrase(l, "day", l.Ordinal(c.From))
+		default:
+			item = phrase(l, "range", phrase(l, "day", l.Ordinal(c.From)), l.Ordinal(c.To))
+		}
+
+		if c.Repeat != 0 {
+			item = describeRepeat(l, item, c, "day")
+		}
+
+		if c.Nearest {
+			item = phrase(l, "nearest weekday", item)
+		}
+
+		items = append(items, item)
+	}
+
+	if months == "" {
+		return phrase(l, "on days", joinWords(l, items))
+	}
+	return phrase(l, "on days"+suffix, joinWords(l, items), months)
+}
+
+// describeDayFromEnd returns the description of a day counted from the end of
+// the month.
+func describeDayFromEnd(l Locale, n int) string {
+	if n == 1 {
+		return l.Phrase("last day")
+	}
+	return phrase(l, "day from end", n, l.Ordinal(n))
+}
+
+// describeMonths returns the description of the months, such as "January and
+// July to September" in English.
+func describeMonths(l Locale, cs components) string {
+	var items []string
+	for _, c := range cs {
+		var item = l.Month(c.From)
+		if c.To != 0 {
+			item = phrase(l, "range", item, l.Month(c.To))
+		}
+
+		if c.Repeat != 0 {
+			item = describeRepeat(l, item, c, "month")
+		}
+
+		items = append(items, item)
+	}
+
+	return joinWords(l, items)
+}
+
+// describeNumbers returns the description of components of the unit described
+// by their numbers, such as "2024 to 2026" in English for the years.
+func describeNumbers(l Locale, cs components, unit string) string {
+	var items []string
+	for _, c := range cs {
+		var item = strconv.Itoa(c.From)
+		if c.To != 0 {
+			item = phrase(l, "range", item, strconv.Itoa(c.To))
+		}
+
+		if c.Repeat != 0 {
+			item = describeRepeat(l, item, c, unit)
+		}
+
+		items = append(items, item)
+	}
+
+	return joinWords(l, items)
+}
+
+// describeRepeat returns the description of a repeated component from the
+// description of its first value or range.
+func describeRepeat(l Locale, item string, c component, unit string) string {
+	var key = "every nth " + unit
+	switch {
+	case c.To != 0:
+		key = "range every " + unit + "s"
+	case c.Repeat == 2:
+		key = "every other " + unit
+	}
+
+	return phrase(l, key, c.Repeat, l.Ordinal(c.Repeat), item)
+}
+
+// describeWeekdays returns the description of the weekdays, such as "Monday to
+// Friday" or "the last Friday of the month" in English.
+func describeWeekdays(l Locale, weekdays weekdayComponents) string {
+	// A run of weekdays to Sunday followed by a run from Monday is
+	// described as a single range wrapping around the week, if it has at
+	// least 3 weekdays as the other ranges of the normalized form.
+	var (
+		runs = componentsOf(weekdays.Values(), 1)
+		last = len(runs) - 1
+	)
+	if last > 0 && runs[0].From == 1 && max(runs[last].From, runs[last].To) == 7 {
+		if to := max(runs[0].From, runs[0].To); to+8-runs[last].From >= 3 {
+			runs[last].To = to
+			runs = runs[1:]
+		}
+	}
+
+	var items []string
+	for _, c := range runs {
+		var item = l.Weekday(c.From)
+		if c.To != 0 {
+			item = phrase(l, "range", item, l.Weekday(c.To))
+		}
+		items = append(items, item)
+	}
+
+	var occurrences []string
+	for _, c := range weekdays.normalized() {
+		if c.Nth != 0 {
+			occurrences = append(occurrences, describeOccurrence(l, c))
+		}
+	}
+
+	if len(occurrences) != 0 {
+		items = append(items, phrase(l, "nth weekdays", joinWords(l, occurrences)))
+	}
+
+	return joinWords(l, items)
+}
+
+// describeOccurrence returns the description of an occurrence of a weekday,
+// such as "the second Tuesday" in English.
+func describeOccurrence(l Locale, c weekdayComponent) string {
+	var key = "nth weekday"
+	switch {
+	case c.Nth == 1:
+		key = "first weekday"
+	case c.Nth == -1:
+		key = "last weekday"
+	case c.Nth < 0:
+		key = "nth to last weekday"
+	}
+
+	var n = max(c.Nth, -c.Nth)
+	return phrase(l, key, n, l.Ordinal(n), l.Weekday(c.From))
+}
+
+// hasRepeat returns whether one of the components is repeated.
+func hasRepeat(cs components) bool {
+	for _, c := range cs {
+		if c.Repeat != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stepOf returns the difference between the consecutive values if it is
+// constant, or 0.
+func stepOf(values []int) (step int) {
+	if len(values) < 2 {
+		return 0
+	}
+
+	step = values[1] - values[0]
+	for i := 2; i < len(values); i++ {
+		if values[i]-values[i-1] != step {
+			return 0
+		}
+	}
+
+	return step
+}
+
+// formatTime returns the time of day as HH:MM, or HH:MM:SS if the seconds are
+// needed.
+func formatTime(hour, minute, second int, withSeconds bool) string {
+	var buf bytes.Buffer
+
+	formatNumber(&buf, hour, 1, 2)
+	buf.WriteString(":")
+	formatNumber(&buf, minute, 1, 2)
+	if withSeconds {
+		buf.WriteString(":")
+		formatNumber(&buf, second, microsecondsPerSecond, 2)
+	}
+
+	return buf.String()
+}
+
+// formatScaled returns the value scaled by scale, without padding.
+func formatScaled(v, scale int) string {
+	var buf bytes.Buffer
+	formatNumber(&buf, v, scale, 1)
+	return buf.String()
+}
+
+// phrase formats the phrase of the locale with the arguments.
+func phrase(l Locale, key string, args ...any) string {
+	return fmt.Sprintf(l.Phrase(key), args...)
+}
+
+// joinWords joins the items as a list in the language of the locale, such as
+// "a, b and c" in English.
+func joinWords(l Locale, items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + l.Phrase("and") + " " + items[len(items)-1]
+}
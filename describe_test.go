@@ -0,0 +1,70 @@
+package zcalendar
+
+import (
+	"testing"
+)
+
+func TestExpression_Describe(t *testing.T) {
+	type Case struct {
+		in  string
+		out string
+	}
+
+	for _, c := range []Case{
+		{in: "Mon..Fri *-*-01/2 08..18:00/15:00 Europe/Paris", out: "every 15 minutes from 08:00 to 18:45 on odd days of the month, Monday to Friday, Paris time"},
+		{in: "daily", out: "at 00:00"},
+		{in: "hourly", out: "every hour"},
+		{in: "*-*-* *:*:*", out: "every second"},
+		{in: "*:05/15", out: "every 15 minutes starting at 00:05"},
+		{in: "00/6:00", out: "every 6 hours"},
+		{in: "08..18:30", out: "every hour from 08:30 to 18:30"},
+		{in: "08,12,18:30", out: "at 08:30, 12:30 and 18:30"},
+		{in: "*:00,20,45", out: "at minutes 0, 20 and 45"},
+		{in: "08,12,18,20:00/30", out: "every 30 minutes during hours 8, 12, 18 and 20"},
+		{in: "*-*-* 08:00:00.5,30", out: "at 08:00:00.5 and 08:00:30"},
+		{in: "*-*-* 08:00:00..01/0.00001", out: "at seconds 0 to 1 every 0.00001 seconds during minute 0 during hour 8"},
+		{in: "Mon,Wed,Fri 09:00 UTC", out: "at 09:00 on Monday, Wednesday and Friday, UTC"},
+		{in: "Fri..Mon 22:00", out: "at 22:00 on Friday to Monday"},
+		{in: "Sun..Tue,Thu 22:00", out: "at 22:00 on Thursday and Sunday to Tuesday"},
+		{in: "Mon/2 12:00", out: "at 12:00 on Monday, Wednesday, Friday and Sunday"},
+		{in: "Tue#2,Fri#-1 09:00", out: "at 09:00 on the 2nd Tuesday and the last Friday of the month"},
+		{in: "Mon,Thu#1 09:00", out: "at 09:00 on Monday and the first Thursday of the month"},
+		{in: "Sun#-2 *-*-01/2 09:00", out: "at 09:00 on odd days of the month, the 2nd to last Sunday of the month"},
+		{in: "*-*-15W 09:00", out: "at 09:00 on the weekday nearest the 15th of the month"},
+		{in: "*-*~01W 18:00", out: "at 18:00 on the weekday nearest the last day of the month"},
+		{in: "W02/2 *-*-* 09:00", out: "at 09:00 in ISO weeks 2 and every other week after"},
+		{in: "D1,91,182,274 12:00", out: "at 12:00 on days 1, 91, 182 and 274 of the year"},
+		{in: "W01 D366 12:00", out: "at 12:00 in ISO week 1 on day 366 of the year"},
+		{in: "Mon *-05~07/1 12:00", out: "at 12:00 on the last 7 days of May, Monday"},
+		{in: "*-*~01 12:00", out: "at 12:00 on the last day of the month"},
+		{in: "*-*~01..07 12:00", out: "at 12:00 on the last 7 days of the month"},
+		{in: "*-01,07-01 00:00", out: "at 00:00 on the 1st of January and July"},
+		{in: "*-01/3-15 00:00", out: "at 00:00 on the 15th of the month in every 3rd month from January"},
+		{in: "*-*-02/2 10:00", out: "at 10:00 on even days of the month"},
+		{in: "2024/2-*-* 00:00", out: "at 00:00 in every other year from 2024"},
+		{in: "2024..2026-*-22 00:00 America/New_York", out: "at 00:00 on the 22nd of the month in 2024 to 2026, New York time"},
+	} {
+		t.Run(c.in, func(t *testing.T) {
+			out := MustParse(c.in).Describe(English)
+			if out != c.out {
+				t.Errorf("unexpected description: wanted %q, got %q", c.out, out)
+			}
+		})
+	}
+}
+
+func TestSchedule_Describe(t *testing.T) {
+	s := MustParseSchedule("Mon..Fri 09:00\nSat..Sun 10:00")
+
+	out := s.Describe(English)
+	if out != "at 09:00 on Monday to Friday; at 10:00 on Saturday to Sunday" {
+		t.Errorf("unexpected description: got %q", out)
+	}
+
+	s = MustParseSchedule("Mon..Fri 09:00\n!*-12-25")
+
+	out = s.Describe(English)
+	if out != "at 09:00 on Monday to Friday, except at 00:00 on the 25th of December" {
+		t.Errorf("unexpected description: got %q", out)
+	}
+}
@@ -0,0 +1,112 @@
+package zcalendar
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Field is a part of an expression, as reported by a ParseError.
+type Field string
+
+// The fields of an expression.
+const (
+	FieldWeekday  Field = "weekday"
+	FieldYear     Field = "year"
+	FieldMonth    Field = "month"
+	FieldDay      Field = "day"
+	FieldWeek     Field = "week"
+	FieldYearday  Field = "yearday"
+	FieldHour     Field = "hour"
+	FieldMinute   Field = "minute"
+	FieldSecond   Field = "second"
+	FieldTimezone Field = "timezone"
+)
+
+// An ErrorCode identifies the kind of a ParseError. The codes are stable and
+// can be used to translate or handle the errors.
+type ErrorCode string
+
+// The codes of the parse errors.
+const (
+	CodeEmpty             ErrorCode = "empty"
+	CodeTooManyChunks     ErrorCode = "too_many_chunks"
+	CodeInvalidChunk      ErrorCode = "invalid_chunk"
+	CodeInvalidDate       ErrorCode = "invalid_date"
+	CodeInvalidTime       ErrorCode = "invalid_time"
+	CodeInvalidTimezone   ErrorCode = "invalid_timezone"
+	CodeInvalidWeekday    ErrorCode = "invalid_weekday"
+	CodeInvalidValue      ErrorCode = "invalid_value"
+	CodeNegativeValue     ErrorCode = "negative_value"
+	CodeInvalidRepeat     ErrorCode = "invalid_repeat"
+	CodeInvalidRange      ErrorCode = "invalid_range"
+	CodeInvalidBounds     ErrorCode = "invalid_bounds"
+	CodeOutOfBounds       ErrorCode = "out_of_bounds"
+	CodeUnsatisfiable     ErrorCode = "unsatisfiable"
+	CodeInvalidOccurrence ErrorCode = "invalid_occurrence"
+)
+
+// A ParseError is returned when an expression can't be parsed. It locates the
+// invalid text in the parsed string, so it can be highlighted.
+type ParseError struct {
+	// Offset and Length are the position of the invalid text in the
+	// parsed string, in bytes.
+	Offset int
+	Length int
+
+	// Field is the field of the expression in which the error occurred,
+	// or empty if the error isn't specific to a field.
+	Field Field
+
+	// Chunk is the index of the whitespace-separated chunk in which the
+	// error occurred, or -1 if the error isn't specific to a chunk.
+	Chunk int
+
+	// Code identifies the kind of error.
+	Code ErrorCode
+
+	// Err is the underlying error, if any.
+	Err error
+
+	msg string
+}
+
+// newParseError creates a parse error for the text at offset.
+func newParseError(code ErrorCode, offset, length int, cause error, format string, args ...any) *ParseError {
+	return &ParseError{
+		Offset: offset,
+		Length: length,
+		Chunk:  -1,
+		Code:   code,
+		Err:    cause,
+		msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// wrapParseError adds the context of the caller to a parse error, prefixing
+// its message and shifting its offset by the position of the text it was
+// returned for.
+func wrapParseError(err error, offset int, format string, args ...any) error {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		perr = newParseError(CodeInvalidValue, 0, 0, err, "%v", err)
+	}
+
+	wrapped := *perr
+	wrapped.Offset += offset
+	wrapped.msg = fmt.Sprintf(format, args...) + ": " + perr.msg
+
+	return &wrapped
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.msg == "" {
+		return string(e.Code)
+	}
+	return e.msg
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
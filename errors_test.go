@@ -0,0 +1,88 @@
+package zcalendar
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse_Errors(t *testing.T) {
+	type Case struct {
+		in     string
+		offset int
+		length int
+		field  Field
+		chunk  int
+		code   ErrorCode
+	}
+
+	for _, c := range []Case{
+		{in: "", offset: 0, length: 0, chunk: -1, code: CodeEmpty},
+		{in: "Mon 2006-01-02 15:04:05 UTC hello", offset: 28, length: 5, chunk: 4, code: CodeTooManyChunks},
+		{in: "Mon,Abc 12:00", offset: 4, length: 3, field: FieldWeekday, chunk: 0, code: CodeInvalidWeekday},
+		{in: "Mon..Fri,Sun..Abc", offset: 14, length: 3, field: FieldWeekday, chunk: 0, code: CodeInvalidWeekday},
+		{in: "Mon,Tue#6 12:00", offset: 8, length: 1, field: FieldWeekday, chunk: 0, code: CodeInvalidOccurrence},
+		{in: "Fri#-x 12:00", offset: 4, length: 2, field: FieldWeekday, chunk: 0, code: CodeInvalidOccurrence},
+		{in: "Sat,Mon/x 12:00", offset: 8, length: 1, field: FieldWeekday, chunk: 0, code: CodeInvalidRepeat},
+		{in: "Mon/2#1 12:00", offset: 4, length: 1, field: FieldWeekday, chunk: 0, code: CodeInvalidRepeat},
+		{in: "Mon  2006-1-02..1x", offset: 16, length: 2, field: FieldDay, chunk: 1, code: CodeInvalidValue},
+		{in: "2006-a-02", offset: 5, length: 1, field: FieldMonth, chunk: 0, code: CodeInvalidValue},
+		{in: "*-*~02..01 12:00", offset: 4, length: 6, field: FieldDay, chunk: 0, code: CodeInvalidBounds},
+		{in: "1-2-3-4", offset: 0, length: 7, chunk: 0, code: CodeInvalidDate},
+		{in: "*-*-* 12:00/x", offset: 12, length: 1, field: FieldMinute, chunk: 1, code: CodeInvalidRepeat},
+		{in: "*-*-* 12:00:1.a", offset: 12, length: 3, field: FieldSecond, chunk: 1, code: CodeInvalidValue},
+		{in: "1:2:3:4", offset: 0, length: 7, chunk: 0, code: CodeInvalidTime},
+		{in: "daily Nowhere/City", offset: 6, length: 12, field: FieldTimezone, chunk: 1, code: CodeInvalidTimezone},
+		{in: "12:00 UTC UTC", offset: 10, length: 3, chunk: 2, code: CodeInvalidChunk},
+		{in: "*-*-* 12,25:00", offset: 9, length: 2, field: FieldHour, chunk: 1, code: CodeOutOfBounds},
+		{in: "*-02-30,31", offset: 0, length: 10, field: FieldDay, chunk: 0, code: CodeUnsatisfiable},
+		{in: "*-*-01,01..05W", offset: 7, length: 7, field: FieldDay, chunk: 0, code: CodeInvalidValue},
+		{in: "*-*-32W", offset: 4, length: 2, field: FieldDay, chunk: 0, code: CodeOutOfBounds},
+		{in: "*-02-30W", offset: 0, length: 8, field: FieldDay, chunk: 0, code: CodeUnsatisfiable},
+		{in: "W54 12:00", offset: 1, length: 2, field: FieldWeek, chunk: 0, code: CodeOutOfBounds},
+		{in: "12:00 D1,367", offset: 9, length: 3, field: FieldYearday, chunk: 1, code: CodeOutOfBounds},
+	} {
+		t.Run(c.in, func(t *testing.T) {
+			_, err := Parse(c.in)
+
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if perr.Offset != c.offset || perr.Length != c.length {
+				t.Errorf("unexpected position: wanted %d+%d, got %d+%d", c.offset, c.length, perr.Offset, perr.Length)
+			}
+
+			if perr.Field != c.field || perr.Chunk != c.chunk || perr.Code != c.code {
+				t.Errorf("unexpected error: wanted %s/%d/%s, got %s/%d/%s", c.field, c.chunk, c.code, perr.Field, perr.Chunk, perr.Code)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	_, err := ParseSchedule("daily\n*-*-* 25..2:00")
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+
+	if perr.Offset != 12 || perr.Length != 5 || perr.Field != FieldHour || perr.Code != CodeInvalidBounds {
+		t.Errorf("unexpected error: got %+v", perr)
+	}
+
+	if err.Error() != "parsing expression 1: parsing hours: parsing range 0: invalid bounds" {
+		t.Errorf("unexpected message: got %s", err)
+	}
+
+	// The offsets of the exclusions account for their prefix.
+	_, err = ParseSchedule("daily\n !*-*-* 25..2:00")
+	if !errors.As(err, &perr) {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+
+	if perr.Offset != 14 || perr.Length != 5 || perr.Field != FieldHour {
+		t.Errorf("unexpected error: got %+v", perr)
+	}
+}
@@ -3,11 +3,13 @@ package zcalendar
 import (
 	"bytes"
 	"database/sql/driver"
-	"errors"
 	"fmt"
-	"reflect"
+	"hash/fnv"
+	"iter"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // An Expression is the Go representation of a Calendar Event as per Systemd's
@@ -28,6 +30,18 @@ type Expression struct {
 
 	// Fourth part of the expression is the timezone.
 	timezone *time.Location
+
+	// Optional parts of the expression are the ISO weeks and the days of
+	// the year, matching all their values when empty.
+	weeks    components
+	yeardays components
+
+	// Whether the expression excludes its points in time from a schedule,
+	// as written with a "!" prefix.
+	excluded bool
+
+	// The values of the components, computed when creating the expression.
+	sets sets
 }
 
 // Non unit-related boundaries.
@@ -44,7 +58,15 @@ var (
 	allDays     = components{{From: 1, To: 31}}
 	allHours    = components{{From: 0, To: 23}}
 	allMinutes  = components{{From: 0, To: 59}}
-	allSeconds  = components{{From: 0, To: 59}}
+	allSeconds  = components{{From: 0, To: 59 * microsecondsPerSecond}}
+	allWeeks    = components{{From: 1, To: 53}}
+	allYeardays = components{{From: 1, To: 366}}
+)
+
+// The seconds are stored with a microsecond resolution.
+const (
+	microsecondsPerSecond = 1000000
+	maxSeconds            = 60*microsecondsPerSecond - 1
 )
 
 // The default values for easy manipulation.
@@ -59,11 +81,23 @@ var (
 	defaulttimezone = time.Local
 )
 
+// shorthands list the special expressions supported by Systemd and their
+// expanded form.
+var shorthands = map[string]string{
+	"minutely":     "*-*-* *:*:00",
+	"hourly":       "*-*-* *:00:00",
+	"daily":        "*-*-* 00:00:00",
+	"monthly":      "*-*-01 00:00:00",
+	"weekly":       "Mon *-*-* 00:00:00",
+	"yearly":       "*-01-01 00:00:00",
+	"annually":     "*-01-01 00:00:00",
+	"quarterly":    "*-01,04,07,10-01 00:00:00",
+	"semiannually": "*-01,07-01 00:00:00",
+}
+
 // Parse a raw string into an expression. Follows Systemd's Calendar Events
 // specification with some exceptions:
 // - Any timezone can be specified, not only UTC and local
-// - Sub-second aren't handled
-// - The end-of-month token isn't handled
 //
 // Original implementation can be found here: https://github.com/systemd/systemd/blob/master/src/basic/calendarspec.c#L879
 func Parse(raw string) (exp Expression, err error) {
@@ -79,122 +113,228 @@ func Parse(raw string) (exp Expression, err error) {
 		timezone: defaulttimezone,
 	}
 
-	chunks := strings.Fields(raw)
+	chunks, offsets := fields(raw)
 
 	// If there is no chunk to handle, the expression is composed of
 	// whitespaces and thus invalid.
 	if len(chunks) == 0 {
-		return exp, errors.New("empty expression")
+		return exp, newParseError(CodeEmpty, 0, len(raw), nil, "empty expression")
 	}
 
-	// If there is more than 4 chunks, the expression has whitespaces at
-	// the wrong places, or is simply not an expression.
+	// The index of the chunks in the original expression, which differs
+	// from their index in the stack once chunks are shifted out of it.
+	var indexes = make([]int, len(chunks))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	// The ISO weeks and the days of the year are prefixed by a letter, so
+	// they can be anywhere in the expression and are taken out of the
+	// stack first.
+	for i := 0; i < len(chunks); {
+		var (
+			chunk  = chunks[i]
+			field  Field
+			target *components
+			b      bounds
+		)
+
+		switch {
+		case len(chunk) < 2 || chunk[1] < '0' || chunk[1] > '9':
+			i++
+			continue
+		case chunk[0] == 'W' && exp.weeks == nil:
+			field, target, b = FieldWeek, &exp.weeks, weeksBounds
+		case chunk[0] == 'D' && exp.yeardays == nil:
+			field, target, b = FieldYearday, &exp.yeardays, yeardaysBounds
+		default:
+			i++
+			continue
+		}
+
+		*target, err = parseBoundedComponents(chunk[1:], b)
+		if err != nil {
+			perr := wrapParseError(err, offsets[i]+1, "parsing %ss", field).(*ParseError)
+			perr.Field = field
+			perr.Chunk = indexes[i]
+			return exp, perr
+		}
+
+		chunks = slices.Delete(chunks, i, i+1)
+		offsets = slices.Delete(offsets, i, i+1)
+		indexes = slices.Delete(indexes, i, i+1)
+	}
+
+	// If there is more than 4 other chunks, the expression has whitespaces
+	// at the wrong places, or is simply not an expression.
 	if len(chunks) > 4 {
-		return exp, errors.New("too many components")
+		perr := newParseError(CodeTooManyChunks, offsets[4], len(raw)-offsets[4], nil, "too many components")
+		perr.Chunk = indexes[4]
+		return exp, perr
+	}
+
+	// If the first chunk is a shorthand, replace it by its expanded form.
+	// Only a timezone can follow a shorthand, which is enforced by the
+	// parsing of the expanded chunks. Shorthands aren't kept in the
+	// expression, which is always marshaled to its canonical form.
+	if len(chunks) != 0 {
+		if expanded, ok := shorthands[strings.ToLower(chunks[0])]; ok {
+			parts := strings.Fields(expanded)
+			for range parts[1:] {
+				offsets = append([]int{offsets[0]}, offsets...)
+				indexes = append([]int{indexes[0]}, indexes...)
+			}
+			chunks = append(parts, chunks[1:]...)
+		}
 	}
 
-	// TODO Handle shortcuts.
+	// failed sets the position of an error returned for a part of the
+	// first chunk, starting at offset in the chunk.
+	var failed = func(err error, field Field, offset int, format string) error {
+		err = wrapParseError(err, offsets[0]+offset, format)
+
+		perr := err.(*ParseError)
+		perr.Field = field
+		perr.Chunk = indexes[0]
+		return perr
+	}
 
 	// If the first chunk has a neither a dash or a comma, then it can't be
 	// a date or time, and a timezone can't be the first item, so it has to
-	// be weekdays.
-	if !strings.ContainsAny(chunks[0], "-:") {
+	// be weekdays. Occurrences counted from the end of the month have a
+	// dash, but come after a "#". A chunk left alone by the ISO weeks or
+	// the days of the year may also be a timezone, as in "W01 UTC".
+	if len(chunks) != 0 && (!strings.ContainsAny(chunks[0], "-~:") || strings.Contains(chunks[0], "#")) && !isLoneTimezone(exp, chunks) {
 		exp.weekdays, err = parseWeekdayComponents(chunks[0])
 		if err != nil {
-			return exp, fmt.Errorf(`parsing weekdays: %w`, err)
+			return exp, failed(err, FieldWeekday, 0, "parsing weekdays")
 		}
 
 		// If the first chunk is successfully parsed, shift it out of
 		// the stack so the rest of the steps always work on the first
 		// chunk of the stack.
-		chunks = chunks[1:]
+		chunks, offsets, indexes = chunks[1:], offsets[1:], indexes[1:]
 	}
 
-	// If the first chunk contains a dash, it must be a date.
-	if len(chunks) != 0 && strings.Contains(chunks[0], "-") {
-		parts := strings.Split(chunks[0], "-")
+	// If the first chunk contains a dash or a tilde, it must be a date.
+	if len(chunks) != 0 && strings.ContainsAny(chunks[0], "-~") {
+		var (
+			date    = chunks[0]
+			days    string
+			fromEnd bool
+		)
+
+		// The end-of-month token replaces the dash between the months
+		// and the days, and indicates the days are counted from the end
+		// of the month.
+		index := strings.Index(date, "~")
+		if index != -1 {
+			date, days, fromEnd = date[:index], date[index+1:], true
+		}
+
+		parts, starts := split(date, "-")
+		if fromEnd {
+			parts, starts = append(parts, days), append(starts, index+1)
+		}
 
 		// A date is composed a most of 3 parts: years, months, days.
 		// There is no need to check for the one part case, as it
 		// wouldn't have any dash in it, and thus wouldn't enter this
 		// case.
 		if len(parts) > 3 {
-			return exp, errors.New("invalid parts component")
+			perr := newParseError(CodeInvalidDate, offsets[0], len(chunks[0]), nil, "invalid parts component")
+			perr.Chunk = indexes[0]
+			return exp, perr
 		}
 
 		// The year is optional, so add it if missing.
 		if len(parts) == 2 {
-			parts = append([]string{"*"}, parts...)
+			parts, starts = append([]string{"*"}, parts...), append([]int{0}, starts...)
 		}
 
 		if parts[0] != "*" {
-			exp.years, err = parseComponents(parts[0])
+			exp.years, err = parseBoundedComponents(parts[0], yearsBounds)
 			if err != nil {
-				return exp, fmt.Errorf(`parsing years: %w`, err)
+				return exp, failed(err, FieldYear, starts[0], "parsing years")
 			}
 		}
 
 		if parts[1] != "*" {
-			exp.months, err = parseComponents(parts[1])
+			exp.months, err = parseBoundedComponents(parts[1], monthsBounds)
 			if err != nil {
-				return exp, fmt.Errorf(`parsing months: %w`, err)
+				return exp, failed(err, FieldMonth, starts[1], "parsing months")
 			}
 		}
 
 		if parts[2] != "*" {
-			exp.days, err = parseComponents(parts[2])
+			exp.days, err = parseBoundedComponents(parts[2], daysBounds)
 			if err != nil {
-				return exp, fmt.Errorf(`parsing days: %w`, err)
+				return exp, failed(err, FieldDay, starts[2], "parsing days")
 			}
+
+			for i := range exp.days {
+				exp.days[i].FromEnd = fromEnd
+			}
+		}
+
+		// Values out of the months are never matched, so an expression
+		// whose days are in none of its months can never fire.
+		if !exp.hasDays() {
+			perr := newParseError(CodeUnsatisfiable, offsets[0], len(chunks[0]), nil, "unsatisfiable date: no month has these days")
+			perr.Field = FieldDay
+			perr.Chunk = indexes[0]
+			return exp, perr
 		}
 
-		chunks = chunks[1:]
+		chunks, offsets, indexes = chunks[1:], offsets[1:], indexes[1:]
 	}
 
 	// If the first chunk contains a comma, it myst be a time.
 	if len(chunks) != 0 && strings.Contains(chunks[0], ":") {
-		parts := strings.Split(chunks[0], ":")
+		parts, starts := split(chunks[0], ":")
 
 		// A time is composed at most of 3 parts: hours, minutes,
 		// seconds. There is no need to check for the one part case, as
 		// for the date chunk.
 		if len(parts) > 3 {
-			return exp, errors.New("invalid time component")
+			perr := newParseError(CodeInvalidTime, offsets[0], len(chunks[0]), nil, "invalid time component")
+			perr.Chunk = indexes[0]
+			return exp, perr
 		}
 
 		// Seconds are optional, so add them if missing.
 		if len(parts) == 2 {
-			parts = append(parts, "00")
+			parts, starts = append(parts, "00"), append(starts, len(chunks[0]))
 		}
 
 		if parts[0] == "*" {
 			exp.hours = allHours
 		} else {
-			exp.hours, err = parseComponents(parts[0])
+			exp.hours, err = parseBoundedComponents(parts[0], hoursBounds)
 			if err != nil {
-				return exp, fmt.Errorf(`parsing hours: %w`, err)
+				return exp, failed(err, FieldHour, starts[0], "parsing hours")
 			}
 		}
 
 		if parts[1] == "*" {
 			exp.minutes = allMinutes
 		} else {
-			exp.minutes, err = parseComponents(parts[1])
+			exp.minutes, err = parseBoundedComponents(parts[1], minutesBounds)
 			if err != nil {
-				return exp, fmt.Errorf(`parsing minutes: %w`, err)
+				return exp, failed(err, FieldMinute, starts[1], "parsing minutes")
 			}
 		}
 
 		if parts[2] == "*" {
 			exp.seconds = allSeconds
 		} else {
-			exp.seconds, err = parseComponents(parts[2])
+			exp.seconds, err = parseBoundedComponents(parts[2], secondsBounds)
 			if err != nil {
-				return exp, fmt.Errorf(`parsing seconds: %w`, err)
+				return exp, failed(err, FieldSecond, starts[2], "parsing seconds")
 			}
 		}
 
-		chunks = chunks[1:]
+		chunks, offsets, indexes = chunks[1:], offsets[1:], indexes[1:]
 	}
 
 	// If there is still a chunk in the stack at this point it must be a
@@ -202,20 +342,53 @@ func Parse(raw string) (exp Expression, err error) {
 	if len(chunks) != 0 {
 		exp.timezone, err = time.LoadLocation(chunks[0])
 		if err != nil {
-			return exp, fmt.Errorf("invalid chunk %s", chunks[0])
+			perr := newParseError(CodeInvalidTimezone, offsets[0], len(chunks[0]), err, "invalid chunk %s", chunks[0])
+			perr.Field = FieldTimezone
+			perr.Chunk = indexes[0]
+			return exp, perr
 		}
 
-		chunks = chunks[1:]
+		chunks, offsets, indexes = chunks[1:], offsets[1:], indexes[1:]
 	}
 
 	// At this point, remaining items indicate unparsable chunks.
 	if len(chunks) != 0 {
-		return exp, fmt.Errorf("invalid chunk %s", chunks[0])
+		perr := newParseError(CodeInvalidChunk, offsets[0], len(chunks[0]), nil, "invalid chunk %s", chunks[0])
+		perr.Chunk = indexes[0]
+		return exp, perr
 	}
 
+	exp.sets = exp.compile()
+
 	return exp, nil
 }
 
+// isLoneTimezone returns whether the chunks are a single timezone that follows
+// the ISO weeks or the days of the year.
+func isLoneTimezone(exp Expression, chunks []string) bool {
+	if len(chunks) != 1 || (exp.weeks == nil && exp.yeardays == nil) {
+		return false
+	}
+
+	_, err := time.LoadLocation(chunks[0])
+	return err == nil
+}
+
+// optional returns the components of an optional part of the expression, or
+// all if the part isn't set.
+func optional(cs, all components) components {
+	if len(cs) == 0 {
+		return all
+	}
+	return cs
+}
+
+// isRestricted returns whether the components of an optional part of the
+// expression are set and don't match all its values.
+func isRestricted(cs, all components, max int) bool {
+	return len(cs) != 0 && !cs.isAll(all, max, 1)
+}
+
 // MustParse is like Parse but will panic in case of error.
 func MustParse(raw string) (e Expression) {
 	e, err := Parse(raw)
@@ -226,6 +399,39 @@ func MustParse(raw string) (e Expression) {
 	return e
 }
 
+// fields is like strings.Fields, but also returns the byte offset of each
+// field in the string.
+func fields(raw string) (chunks []string, offsets []int) {
+	var start = -1
+	for i, r := range raw {
+		switch {
+		case unicode.IsSpace(r) && start != -1:
+			chunks, offsets = append(chunks, raw[start:i]), append(offsets, start)
+			start = -1
+		case !unicode.IsSpace(r) && start == -1:
+			start = i
+		}
+	}
+
+	if start != -1 {
+		chunks, offsets = append(chunks, raw[start:]), append(offsets, start)
+	}
+
+	return chunks, offsets
+}
+
+// split is like strings.Split, but also returns the byte offset of each part
+// in the string.
+func split(raw, sep string) (parts []string, offsets []int) {
+	var offset int
+	for _, part := range strings.Split(raw, sep) {
+		parts, offsets = append(parts, part), append(offsets, offset)
+		offset += len(part) + len(sep)
+	}
+
+	return parts, offsets
+}
+
 // UnmarshalText implements the encoding.TextUnmarshaler interface so an
 // expression can unmarshalled from a JSON object.
 func (e *Expression) UnmarshalText(raw []byte) (err error) {
@@ -233,55 +439,144 @@ func (e *Expression) UnmarshalText(raw []byte) (err error) {
 	return err
 }
 
-// MarshalText implement the encoding.TextMarshaler interface.
+// Normalize returns the expression with its components in their canonical
+// form: the values are sorted and deduplicated, full ranges are replaced by
+// wildcards, runs of at least 3 consecutive values are written as ranges, and
+// progressions of at least 3 values up to the end of the unit as repeats.
+// Equivalent expressions are normalized identically.
+func (e Expression) Normalize() Expression {
+	e.weekdays = e.weekdays.normalized()
+	e.years = e.years.normalized(allYears, MaxYears, 1)
+	e.months = e.months.normalized(allMonths, 12, 1)
+	e.days = e.days.normalized(allDays, 31, 1)
+	e.hours = e.hours.normalized(allHours, 23, 1)
+	e.minutes = e.minutes.normalized(allMinutes, 59, 1)
+	e.seconds = e.seconds.normalized(allSeconds, maxSeconds, microsecondsPerSecond)
+
+	// The optional parts matching all their values are removed.
+	e.weeks = e.weeks.normalized(allWeeks, 53, 1)
+	if e.weeks.isAll(allWeeks, 53, 1) {
+		e.weeks = nil
+	}
+	e.yeardays = e.yeardays.normalized(allYeardays, 366, 1)
+	if e.yeardays.isAll(allYeardays, 366, 1) {
+		e.yeardays = nil
+	}
+
+	// The values are the same, so the sets don't have to be computed
+	// again.
+	return e
+}
+
+// Exclusion returns a copy of the expression marked as an exclusion: in a
+// schedule, its points in time are excluded from those of the other
+// expressions. The mark only affects schedules, and is written as a "!" prefix
+// by Schedule.MarshalText.
+func (e Expression) Exclusion() Expression {
+	e.excluded = true
+	return e
+}
+
+// IsExclusion returns whether the expression is marked as an exclusion.
+func (e Expression) IsExclusion() bool {
+	return e.excluded
+}
+
+// Equal returns whether both expressions match the same values in each of their
+// fields, have the same timezone and are both exclusions or not, whatever the
+// way they are written. For example, "*-*-* 0..23:00" is equal to "*-*-* *:00".
+func (e Expression) Equal(other Expression) bool {
+	var sets = e.compiled()
+	return e.timezone.String() == other.timezone.String() && e.excluded == other.excluded && sets.equal(other.compiled())
+}
+
+// Hash returns a hash of the expression, which is the same for equal
+// expressions. It is stable across processes, so it can be stored to detect
+// duplicates.
+func (e Expression) Hash() uint64 {
+	var (
+		h    = fnv.New64a()
+		sets = e.compiled()
+	)
+
+	sets.write(h)
+	h.Write([]byte(e.timezone.String()))
+	if e.excluded {
+		h.Write([]byte("!"))
+	}
+	return h.Sum64()
+}
+
+// MarshalText implement the encoding.TextMarshaler interface. The expression is
+// normalized first.
 func (e Expression) MarshalText() (text []byte, err error) {
 	var buf bytes.Buffer
 
+	e = e.Normalize()
+
 	// If there is actually a weekdays specification, write all parts.
-	if !reflect.DeepEqual(e.weekdays, allWeekdays) {
+	if !e.weekdays.isAll() {
 		buf.WriteString(e.weekdays.String())
 		buf.WriteString(" ")
 	}
 
-	if reflect.DeepEqual(e.years, allYears) {
+	// The optional parts are only written when they are set, which they
+	// aren't anymore if they match all their values.
+	if e.weeks != nil {
+		buf.WriteString("W")
+		buf.WriteString(e.weeks.String())
+		buf.WriteString(" ")
+	}
+
+	if e.yeardays != nil {
+		buf.WriteString("D")
+		buf.WriteString(e.yeardays.String())
+		buf.WriteString(" ")
+	}
+
+	if e.years.isAll(allYears, MaxYears, 1) {
 		buf.WriteString("*")
 	} else {
 		buf.WriteString(e.years.String())
 	}
 	buf.WriteString("-")
 
-	if reflect.DeepEqual(e.months, allMonths) {
+	if e.months.isAll(allMonths, 12, 1) {
 		buf.WriteString("*")
 	} else {
 		buf.WriteString(e.months.String())
 	}
-	buf.WriteString("-")
+	if e.days.fromEnd() {
+		buf.WriteString("~")
+	} else {
+		buf.WriteString("-")
+	}
 
-	if reflect.DeepEqual(e.days, allDays) {
+	if !e.days.fromEnd() && e.days.isAll(allDays, 31, 1) {
 		buf.WriteString("*")
 	} else {
 		buf.WriteString(e.days.String())
 	}
 	buf.WriteString(" ")
 
-	if reflect.DeepEqual(e.hours, allHours) {
+	if e.hours.isAll(allHours, 23, 1) {
 		buf.WriteString("*")
 	} else {
 		buf.WriteString(e.hours.String())
 	}
 	buf.WriteString(":")
 
-	if reflect.DeepEqual(e.minutes, allMinutes) {
+	if e.minutes.isAll(allMinutes, 59, 1) {
 		buf.WriteString("*")
 	} else {
 		buf.WriteString(e.minutes.String())
 	}
 	buf.WriteString(":")
 
-	if reflect.DeepEqual(e.seconds, allSeconds) {
+	if e.seconds.isAll(allSeconds, maxSeconds, microsecondsPerSecond) {
 		buf.WriteString("*")
 	} else {
-		buf.WriteString(e.seconds.String())
+		buf.Write(e.seconds.marshalScaled(microsecondsPerSecond))
 	}
 
 	if e.timezone != time.Local {
@@ -321,6 +616,117 @@ func (e Expression) Value() (val driver.Value, err error) {
 	return e.MarshalText()
 }
 
+// daysIn returns the number of days in the month of the year.
+func daysIn(year, month int) int {
+	return time.Date(year, time.Month(month+1), 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// hasDays returns whether one of the days of the expression exists in one of
+// its months, in any year.
+func (e Expression) hasDays() bool {
+	for _, month := range e.months.Values(12) {
+		// 2000 is a leap year, so February has all its days.
+		if len(e.days.Values(daysIn(2000, month))) != 0 || len(e.days.nearestDays(daysIn(2000, month), 1)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayOf returns the weekday of the date.
+func weekdayOf(year, month, day int) int {
+	weekday := int(time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Weekday())
+	// Go's weekdays range is Sunday=0..Saturday=6, while our weekdays are Monday=1..Sunday=7
+	if weekday == 0 {
+		weekday = 7
+	}
+	return weekday
+}
+
+// IsSatisfiable returns whether at least one point in time between MinYears
+// and MaxYears satisfies the expression. As the days of the months of a year
+// only depend on whether it is a leap year and on the weekday of its first
+// day, each kind of year is only checked once rather than scanning the dates.
+func (e Expression) IsSatisfiable() bool {
+	var sets = e.compiled()
+
+	if sets.hours == 0 || sets.minutes == 0 || (sets.seconds == 0 && len(sets.subseconds) == 0) {
+		return false
+	}
+
+	var checked [2][8]bool // Indexed by leap year and weekday of January 1st.
+	for year, _, ok := sets.years.Next(MinYears); ok && year <= MaxYears; year, _, ok = sets.years.Next(year + 1) {
+		var leap, first = 0, weekdayOf(year, 1, 1)
+		if daysIn(year, 2) == 29 {
+			leap = 1
+		}
+
+		if checked[leap][first] {
+			continue
+		}
+		checked[leap][first] = true
+
+		for month := 1; month <= 12; month++ {
+			if !sets.months.Contains(month) {
+				continue
+			}
+
+			// Compute the days of the month falling on one of the
+			// weekdays, ISO weeks and days of the year, which must
+			// intersect the days.
+			var (
+				days    = daysIn(year, month)
+				weekday = weekdayOf(year, month, 1)
+				matches bitset
+			)
+
+			for day := 1; day <= days; day++ {
+				if sets.containsDay(year, month, day, days) {
+					matches |= 1 << day
+				}
+			}
+
+			if sets.days[days-28][weekday-1]&matches != 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Matches returns whether the point in time satisfies the expression.
+func (e Expression) Matches(d time.Time) bool {
+	d = d.In(e.timezone)
+
+	// The expression has a microsecond resolution, so any point in time
+	// with a finer precision can't match.
+	if d.Nanosecond()%1000 != 0 {
+		return false
+	}
+
+	var (
+		second = d.Second()*microsecondsPerSecond + d.Nanosecond()/1000
+
+		sets = e.compiled()
+	)
+
+	return sets.containsDate(d.Year(), int(d.Month()), d.Day()) &&
+		sets.hours.Contains(d.Hour()) &&
+		sets.minutes.Contains(d.Minute()) &&
+		sets.containsSecond(second)
+}
+
+// compiled returns the sets of values of the expression. They are computed
+// once by all the functions of the package creating expressions, so they are
+// only computed on each call, with allocations, for the zero Expression.
+func (e Expression) compiled() sets {
+	if !e.sets.compiled {
+		return e.compile()
+	}
+	return e.sets
+}
+
 // Next returns the next point in time that will satisfy the schedule that is
 // strictly after d.
 //
@@ -335,15 +741,16 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 		day    = d.Day()
 		hour   = d.Hour()
 		minute = d.Minute()
-		second = d.Second() + 1
+		second = d.Second()*microsecondsPerSecond + d.Nanosecond()/1000 + 1 // In microseconds.
 
 		diff int
+		sets = e.compiled()
 	)
 
 	// The loop works as follow: each unit is initialized with the value
 	// from d. To prevent d being returned in the case it is a valid value,
-	// the seconds are incremented so the returned value is necessarily
-	// different from d.
+	// the seconds are incremented by a microsecond so the returned value is
+	// necessarily different from d.
 	//
 	// For each unit from the bigest to the smallest, get the next value
 	// allowed by the expression. From this point, there is 3 possibilities:
@@ -358,7 +765,7 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 	// When we reach the end of the loop, we can safely break out and
 	// return the actual values as the next date.
 	for {
-		year, diff, ok = e.years.Next(year, MaxYears)
+		year, diff, ok = sets.years.Next(year)
 		if !ok {
 			return
 		}
@@ -376,7 +783,7 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 			second = 0
 		}
 
-		month, diff, ok = e.months.Next(month, 12)
+		month, diff, ok = sets.months.Next(month)
 		if !ok {
 			return
 		}
@@ -397,15 +804,16 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 			second = 0
 		}
 
-		daysInMonth := time.Date(year, time.Month(month+1), 0, 0, 0, 0, 0, time.UTC).Day()
+		daysInMonth := daysIn(year, month)
 
-		day, diff, ok = e.days.Next(day, daysInMonth)
-		if !ok {
-			return
-		}
-
-		if diff < 0 {
+		// Some months may not have any valid day, in which case the
+		// next month must be checked.
+		day, diff, ok = sets.daysOf(year, month).Next(day)
+		if !ok || diff < 0 {
+			// The first day depends on the month when counting from
+			// the end of the month, so it must be computed again.
 			month++
+			day = 1
 			hour = 0
 			minute = 0
 			second = 0
@@ -418,12 +826,7 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 			second = 0
 		}
 
-		weekday := int(time.Date(year, time.Month(month), day, 0, 0, 0, 0, e.timezone).Weekday())
-		// Go's weekdays range is Sunday=0..Saturday=6, while our weekdays are Monday=1..Sunday=7
-		if weekday == 0 {
-			weekday = 7
-		}
-		if !e.weekdays.Contains(weekday) {
+		if !sets.containsDay(year, month, day, daysInMonth) {
 			day++
 			hour = 0
 			minute = 0
@@ -431,7 +834,7 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 			continue
 		}
 
-		hour, diff, ok = e.hours.Next(hour, 23)
+		hour, diff, ok = sets.hours.Next(hour)
 		if !ok {
 			return
 		}
@@ -448,7 +851,7 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 			second = 0
 		}
 
-		minute, diff, ok = e.minutes.Next(minute, 59)
+		minute, diff, ok = sets.minutes.Next(minute)
 		if !ok {
 			return
 		}
@@ -463,7 +866,7 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 			second = 0
 		}
 
-		second, diff, ok = e.seconds.Next(second, 59)
+		second, diff, ok = sets.nextSecond(second)
 		if !ok {
 			return
 		}
@@ -476,5 +879,191 @@ func (e Expression) Next(d time.Time) (n time.Time, ok bool) {
 		break
 	}
 
-	return time.Date(year, time.Month(month), day, hour, minute, second, 0, e.timezone), true
+	return time.Date(year, time.Month(month), day, hour, minute, second/microsecondsPerSecond, second%microsecondsPerSecond*1000, e.timezone), true
+}
+
+// Prev returns the previous point in time that satisfied the schedule that is
+// strictly before d.
+func (e Expression) Prev(d time.Time) (p time.Time, ok bool) {
+	d = d.In(e.timezone)
+	var before = d
+
+	const lastSecond = 60*microsecondsPerSecond - 1
+
+	var (
+		year   = d.Year()
+		month  = int(d.Month())
+		day    = d.Day()
+		hour   = d.Hour()
+		minute = d.Minute()
+		second = d.Second()*microsecondsPerSecond + d.Nanosecond()/1000 - 1 // In microseconds.
+
+		diff int
+		sets = e.compiled()
+	)
+
+	// The loop works as the one in Next, in reverse: the seconds are
+	// decremented by a microsecond so the returned value is necessarily
+	// different from d, and for each unit from the biggest to the smallest,
+	// get the previous value allowed by the expression:
+	//
+	// - If this value is equal, skip to the next unit,
+	// - If the previous value is smaller than the current one, reset the
+	//   lower units to the last value,
+	// - If the previous value is bigger than the current value, we
+	//   decrement the unit before, reset the lower units to the last value,
+	//   and start over.
+	//
+	// The last day depends on the month, so it is reset to the largest
+	// possible value and adjusted once the month is known.
+	for {
+		year, diff, ok = sets.years.Prev(year)
+		if !ok {
+			return
+		}
+
+		if diff > 0 {
+			ok = false
+			return
+		}
+
+		if diff < 0 {
+			month = 12
+			day = 31
+			hour = 23
+			minute = 59
+			second = lastSecond
+		}
+
+		month, diff, ok = sets.months.Prev(month)
+		if !ok {
+			return
+		}
+
+		if diff > 0 {
+			year--
+			day = 31
+			hour = 23
+			minute = 59
+			second = lastSecond
+			continue
+		}
+
+		if diff < 0 {
+			day = 31
+			hour = 23
+			minute = 59
+			second = lastSecond
+		}
+
+		daysInMonth := daysIn(year, month)
+		if day > daysInMonth {
+			day = daysInMonth
+		}
+
+		// Some months may not have any valid day, in which case the
+		// previous month must be checked.
+		day, diff, ok = sets.daysOf(year, month).Prev(day)
+		if !ok || diff > 0 {
+			month--
+			day = 31
+			hour = 23
+			minute = 59
+			second = lastSecond
+			continue
+		}
+
+		if diff < 0 {
+			hour = 23
+			minute = 59
+			second = lastSecond
+		}
+
+		if !sets.containsDay(year, month, day, daysInMonth) {
+			day--
+			hour = 23
+			minute = 59
+			second = lastSecond
+			continue
+		}
+
+		hour, diff, ok = sets.hours.Prev(hour)
+		if !ok {
+			return
+		}
+
+		if diff > 0 {
+			day--
+			minute = 59
+			second = lastSecond
+			continue
+		}
+
+		if diff < 0 {
+			minute = 59
+			second = lastSecond
+		}
+
+		minute, diff, ok = sets.minutes.Prev(minute)
+		if !ok {
+			return
+		}
+
+		if diff > 0 {
+			hour--
+			second = lastSecond
+			continue
+		}
+
+		if diff < 0 {
+			second = lastSecond
+		}
+
+		second, diff, ok = sets.prevSecond(second)
+		if !ok {
+			return
+		}
+
+		if diff > 0 {
+			minute--
+			continue
+		}
+
+		// A time skipped by a change to daylight saving time is
+		// moved after the change, possibly after d, in which case the
+		// search continues before it.
+		p = time.Date(year, time.Month(month), day, hour, minute, second/microsecondsPerSecond, second%microsecondsPerSecond*1000, e.timezone)
+		if !p.Before(before) {
+			second--
+			continue
+		}
+
+		return p, true
+	}
+}
+
+// Occurrences returns an iterator over the points in time that satisfy the
+// expression between from, included, and to, excluded.
+func (e Expression) Occurrences(from, to time.Time) iter.Seq[time.Time] {
+	return occurrences(e.Next, from, to)
+}
+
+// occurrences returns an iterator over the points in time returned by
+// successive calls to next between from, included, and to, excluded.
+func occurrences(next func(time.Time) (time.Time, bool), from, to time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		// Next is strictly after the given date, so start right before
+		// from to include it. As the resolution is the microsecond, a
+		// date before from can be returned if it has nanoseconds.
+		n, ok := next(from.Add(-time.Microsecond))
+		for ; ok && n.Before(to); n, ok = next(n) {
+			if n.Before(from) {
+				continue
+			}
+
+			if !yield(n) {
+				return
+			}
+		}
+	}
 }
@@ -2,6 +2,7 @@ package zcalendar
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 	"time"
 )
@@ -14,29 +15,35 @@ func init() {
 	Zulu, _ = time.LoadLocation("Zulu")
 }
 
+// compiled returns the expression with its sets computed, as returned by Parse.
+func compiled(e Expression) Expression {
+	e.sets = e.compile()
+	return e
+}
+
 func TestParse(t *testing.T) {
 	testParser(t, Parse, []ParserTestCase{
-		{name: "valid expression", in: "Mon 2006-01-02 15:04:05 Europe/Paris", out: Expression{
+		{name: "valid expression", in: "Mon 2006-01-02 15:04:05 Europe/Paris", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
 			days:     []component{{From: 2}},
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: EuropeParis,
-		}},
-		{name: "optional year", in: "Mon 01-02 15:04:05 Europe/Paris", out: Expression{
+		})},
+		{name: "optional year", in: "Mon 01-02 15:04:05 Europe/Paris", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    defaultYears,
 			months:   []component{{From: 1}},
 			days:     []component{{From: 2}},
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: EuropeParis,
-		}},
-		{name: "optional seconds", in: "Mon 2006-01-02 15:04 Europe/Paris", out: Expression{
+		})},
+		{name: "optional seconds", in: "Mon 2006-01-02 15:04 Europe/Paris", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
@@ -45,8 +52,8 @@ func TestParse(t *testing.T) {
 			minutes:  []component{{From: 4}},
 			seconds:  defaultSeconds,
 			timezone: EuropeParis,
-		}},
-		{name: "wildcards", in: "Mon *-*-* *:*:* Europe/Paris", out: Expression{
+		})},
+		{name: "wildcards", in: "Mon *-*-* *:*:* Europe/Paris", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    allYears,
 			months:   allMonths,
@@ -55,38 +62,38 @@ func TestParse(t *testing.T) {
 			minutes:  allMinutes,
 			seconds:  allSeconds,
 			timezone: EuropeParis,
-		}},
-		{name: "optional timezone", in: "Mon 2006-01-02 15:04:05", out: Expression{
+		})},
+		{name: "optional timezone", in: "Mon 2006-01-02 15:04:05", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
 			days:     []component{{From: 2}},
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: defaulttimezone,
-		}},
-		{name: "optional weekdays", in: "2006-01-02 15:04:05 Europe/Paris", out: Expression{
+		})},
+		{name: "optional weekdays", in: "2006-01-02 15:04:05 Europe/Paris", out: compiled(Expression{
 			weekdays: defaultWeekdays,
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
 			days:     []component{{From: 2}},
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: EuropeParis,
-		}},
-		{name: "optional date", in: "Mon 15:04:05 Europe/Paris", out: Expression{
+		})},
+		{name: "optional date", in: "Mon 15:04:05 Europe/Paris", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    defaultYears,
 			months:   defaultMonths,
 			days:     defaultDays,
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: EuropeParis,
-		}},
-		{name: "optional time", in: "Mon 2006-01-02 Europe/Paris", out: Expression{
+		})},
+		{name: "optional time", in: "Mon 2006-01-02 Europe/Paris", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
@@ -95,28 +102,28 @@ func TestParse(t *testing.T) {
 			minutes:  defaultMinutes,
 			seconds:  defaultSeconds,
 			timezone: EuropeParis,
-		}},
-		{name: "UTC timezone", in: "Mon 2006-01-02 15:04:05 UTC", out: Expression{
+		})},
+		{name: "UTC timezone", in: "Mon 2006-01-02 15:04:05 UTC", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
 			days:     []component{{From: 2}},
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: time.UTC,
-		}},
-		{name: "Zulu timezone", in: "Mon 2006-01-02 15:04:05 Zulu", out: Expression{
+		})},
+		{name: "Zulu timezone", in: "Mon 2006-01-02 15:04:05 Zulu", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
 			days:     []component{{From: 2}},
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: Zulu,
-		}},
-		{name: "date only", in: "2006-01-02", out: Expression{
+		})},
+		{name: "date only", in: "2006-01-02", out: compiled(Expression{
 			weekdays: defaultWeekdays,
 			years:    []component{{From: 2006}},
 			months:   []component{{From: 1}},
@@ -125,18 +132,18 @@ func TestParse(t *testing.T) {
 			minutes:  defaultMinutes,
 			seconds:  defaultSeconds,
 			timezone: defaulttimezone,
-		}},
-		{name: "time only", in: "15:04:05", out: Expression{
+		})},
+		{name: "time only", in: "15:04:05", out: compiled(Expression{
 			weekdays: defaultWeekdays,
 			years:    defaultYears,
 			months:   defaultMonths,
 			days:     defaultDays,
 			hours:    []component{{From: 15}},
 			minutes:  []component{{From: 4}},
-			seconds:  []component{{From: 5}},
+			seconds:  []component{{From: 5 * microsecondsPerSecond}},
 			timezone: defaulttimezone,
-		}},
-		{name: "weekdays only", in: "Mon", out: Expression{
+		})},
+		{name: "weekdays only", in: "Mon", out: compiled(Expression{
 			weekdays: []weekdayComponent{{From: 1}},
 			years:    defaultYears,
 			months:   defaultMonths,
@@ -145,13 +152,86 @@ func TestParse(t *testing.T) {
 			minutes:  defaultMinutes,
 			seconds:  defaultSeconds,
 			timezone: defaulttimezone,
-		}},
+		})},
+		{name: "end of month", in: "Mon *-05~07/1", out: compiled(Expression{
+			weekdays: []weekdayComponent{{From: 1}},
+			years:    defaultYears,
+			months:   []component{{From: 5}},
+			days:     []component{{From: 7, Repeat: 1, FromEnd: true}},
+			hours:    defaultHours,
+			minutes:  defaultMinutes,
+			seconds:  defaultSeconds,
+			timezone: defaulttimezone,
+		})},
+		{name: "end of month without year", in: "02~03", out: compiled(Expression{
+			weekdays: defaultWeekdays,
+			years:    defaultYears,
+			months:   []component{{From: 2}},
+			days:     []component{{From: 3, FromEnd: true}},
+			hours:    defaultHours,
+			minutes:  defaultMinutes,
+			seconds:  defaultSeconds,
+			timezone: defaulttimezone,
+		})},
+		{name: "invalid end of month", in: "2006-01-02~03", err: true},
+		{name: "sub-second value", in: "12:00:01.5", out: compiled(Expression{
+			weekdays: defaultWeekdays,
+			years:    defaultYears,
+			months:   defaultMonths,
+			days:     defaultDays,
+			hours:    []component{{From: 12}},
+			minutes:  []component{{From: 0}},
+			seconds:  []component{{From: 1500000}},
+			timezone: defaulttimezone,
+		})},
+		{name: "sub-second repeat", in: "*:*:0/0.25", out: compiled(Expression{
+			weekdays: defaultWeekdays,
+			years:    defaultYears,
+			months:   defaultMonths,
+			days:     defaultDays,
+			hours:    allHours,
+			minutes:  allMinutes,
+			seconds:  []component{{From: 0, Repeat: 250000}},
+			timezone: defaulttimezone,
+		})},
+		{name: "too precise sub-second", in: "12:00:01.0000001", err: true},
+		{name: "shorthand", in: "weekly", out: compiled(Expression{
+			weekdays: []weekdayComponent{{From: 1}},
+			years:    defaultYears,
+			months:   defaultMonths,
+			days:     defaultDays,
+			hours:    []component{{From: 0}},
+			minutes:  []component{{From: 0}},
+			seconds:  []component{{From: 0}},
+			timezone: defaulttimezone,
+		})},
+		{name: "shorthand with timezone", in: "quarterly Europe/Paris", out: compiled(Expression{
+			weekdays: defaultWeekdays,
+			years:    defaultYears,
+			months:   []component{{From: 1}, {From: 4}, {From: 7}, {From: 10}},
+			days:     []component{{From: 1}},
+			hours:    []component{{From: 0}},
+			minutes:  []component{{From: 0}},
+			seconds:  []component{{From: 0}},
+			timezone: EuropeParis,
+		})},
+		{name: "shorthand followed by time", in: "daily 12:00", err: true},
 		{name: "empty expression", in: "", err: true},
 		{name: "not an expression", in: "les sanglots longs des violons de l'automne", err: true},
 		{name: "timezone only", in: "Europe/Paris", err: true},
 		{name: "invalid timezone", in: "Mon 2006-01-02 15:04:05 hello", err: true},
 		{name: "too many chunks", in: "Mon 2006-01-02 15:04:05 UTC hello", err: true},
 		{name: "chunk after timezone", in: "Mon 15:04:05 UTC hello", err: true},
+		{name: "year out of bounds", in: "2200-01-01", err: true},
+		{name: "month out of bounds", in: "*-13-01", err: true},
+		{name: "day out of bounds", in: "*-*-00", err: true},
+		{name: "offset out of bounds", in: "*-*~32", err: true},
+		{name: "hour out of bounds", in: "24:00", err: true},
+		{name: "minute out of bounds", in: "12:60", err: true},
+		{name: "second out of bounds", in: "12:00:59.5..60", err: true},
+		{name: "range out of bounds", in: "*-*-* 20..25:00", err: true},
+		{name: "unsatisfiable date", in: "*-02-30", err: true},
+		{name: "unsatisfiable date from end", in: "*-02,04~31", err: true},
 	})
 }
 
@@ -172,7 +252,7 @@ func TestExpression_MarshalText(t *testing.T) {
 				days:     []component{{From: 2}},
 				hours:    []component{{From: 15}},
 				minutes:  []component{{From: 4}},
-				seconds:  []component{{From: 5}},
+				seconds:  []component{{From: 5 * microsecondsPerSecond}},
 				timezone: EuropeParis,
 			},
 			out: "Mon 2006-01-02 15:04:05 Europe/Paris",
@@ -186,7 +266,7 @@ func TestExpression_MarshalText(t *testing.T) {
 				days:     []component{{From: 2}},
 				hours:    []component{{From: 15}},
 				minutes:  []component{{From: 4}},
-				seconds:  []component{{From: 5}},
+				seconds:  []component{{From: 5 * microsecondsPerSecond}},
 				timezone: time.Local,
 			},
 			out: "Mon 2006-01-02 15:04:05",
@@ -205,6 +285,34 @@ func TestExpression_MarshalText(t *testing.T) {
 			},
 			out: "*-*-* *:*:* UTC",
 		},
+		{
+			name: "end of month",
+			in: Expression{
+				weekdays: allWeekdays,
+				years:    allYears,
+				months:   []component{{From: 2}},
+				days:     []component{{From: 3, FromEnd: true}},
+				hours:    []component{{From: 0}},
+				minutes:  []component{{From: 0}},
+				seconds:  []component{{From: 0}},
+				timezone: time.UTC,
+			},
+			out: "*-02~03 00:00:00 UTC",
+		},
+		{
+			name: "sub-second",
+			in: Expression{
+				weekdays: allWeekdays,
+				years:    allYears,
+				months:   allMonths,
+				days:     allDays,
+				hours:    []component{{From: 12}},
+				minutes:  []component{{From: 0}},
+				seconds:  []component{{From: 30 * microsecondsPerSecond, Repeat: 7500000}, {From: 1500000}},
+				timezone: time.UTC,
+			},
+			out: "*-*-* 12:00:01.5,30/7.5 UTC",
+		},
 	}
 
 	for _, c := range cases {
@@ -224,12 +332,38 @@ func TestExpression_MarshalText(t *testing.T) {
 	}
 }
 
+func TestParse_Shorthands(t *testing.T) {
+	for shorthand, expanded := range map[string]string{
+		"minutely":     "*-*-* *:*:00 UTC",
+		"hourly":       "*-*-* *:00:00 UTC",
+		"daily":        "*-*-* 00:00:00 UTC",
+		"monthly":      "*-*-01 00:00:00 UTC",
+		"weekly":       "Mon *-*-* 00:00:00 UTC",
+		"yearly":       "*-01-01 00:00:00 UTC",
+		"annually":     "*-01-01 00:00:00 UTC",
+		"quarterly":    "*-01/3-01 00:00:00 UTC",
+		"semiannually": "*-01,07-01 00:00:00 UTC",
+	} {
+		t.Run(shorthand, func(t *testing.T) {
+			exp, err := Parse(shorthand + " UTC")
+			if err != nil {
+				t.Fatalf("unexpected error parsing expression: %s", err)
+			}
+
+			if exp.String() != expanded {
+				t.Errorf("unexpected output: wanted %s, got %s", expanded, exp.String())
+			}
+		})
+	}
+}
+
 func TestExpression_Next(t *testing.T) {
 	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
 
 	type Case struct {
 		name  string
 		exp   string
+		from  string
 		next  string
 		found bool
 	}
@@ -242,8 +376,36 @@ func TestExpression_Next(t *testing.T) {
 		{name: "next monday", exp: "Mon 00:00:00 UTC", next: "2006-01-09T00:00:00Z", found: true},
 		{name: "next sunday", exp: "Sun 00:00:00 UTC", next: "2006-01-08T00:00:00Z", found: true},
 		{name: "next first monday", exp: "Mon *-*-1..7 00:00:00 UTC", next: "2006-02-06T00:00:00Z", found: true},
+		{name: "next second tuesday", exp: "Tue#2 12:00 UTC", next: "2006-01-10T12:00:00Z", found: true},
+		{name: "next last friday", exp: "Fri#-1 12:00 UTC", next: "2006-01-27T12:00:00Z", found: true},
+		{name: "next last friday with L", exp: "Fri#L 12:00 UTC", next: "2006-01-27T12:00:00Z", found: true},
+		{name: "next fifth monday", exp: "Mon#5 12:00 UTC", next: "2006-01-30T12:00:00Z", found: true},
+		{name: "next first monday by occurrence", exp: "Mon#1 00:00:00 UTC", next: "2006-02-06T00:00:00Z", found: true},
 		{name: "next fortnight", exp: "*-*-1,15 00:00:00 UTC", next: "2006-01-15T00:00:00Z", found: true},
 		{name: "next ten min", exp: "*-*-* *:00/10:00 UTC", next: "2006-01-02T15:10:00Z", found: true},
+		{name: "next last day", exp: "*-*~1 UTC", next: "2006-01-31T00:00:00Z", found: true},
+		{name: "next third last day of february", exp: "*-02~03 UTC", next: "2006-02-26T00:00:00Z", found: true},
+		{name: "next last monday of may", exp: "Mon *-05~07/1 UTC", next: "2006-05-29T00:00:00Z", found: true},
+		{name: "next last day of february", exp: "*-*~1 UTC", from: "2006-01-31T12:00:00Z", next: "2006-02-28T00:00:00Z", found: true},
+		{name: "next thirty-first", exp: "*-*-31 UTC", from: "2006-04-01T00:00:00Z", next: "2006-05-31T00:00:00Z", found: true},
+		{name: "next last days", exp: "*-*~1..2 UTC", next: "2006-01-30T00:00:00Z", found: true},
+		{name: "next quarter second", exp: "*:*:0/0.25 UTC", next: "2006-01-02T15:04:05.25Z", found: true},
+		{name: "next half second", exp: "*-*-* 15:04:05.5 UTC", next: "2006-01-02T15:04:05.5Z", found: true},
+		{name: "next nearest weekday on saturday", exp: "*-*-15W 09:00 UTC", from: "2026-08-01T00:00:00Z", next: "2026-08-14T09:00:00Z", found: true},
+		{name: "next nearest weekday on sunday", exp: "*-*-15W 09:00 UTC", from: "2026-03-01T00:00:00Z", next: "2026-03-16T09:00:00Z", found: true},
+		{name: "next nearest weekday of the first", exp: "*-*-01W 09:00 UTC", from: "2026-07-15T00:00:00Z", next: "2026-08-03T09:00:00Z", found: true},
+		{name: "next nearest weekday of the last day", exp: "*-*~01W 09:00 UTC", from: "2026-05-15T00:00:00Z", next: "2026-05-29T09:00:00Z", found: true},
+		{name: "next nearest weekday of a missing day", exp: "*-*-31W 09:00 UTC", from: "2026-01-31T10:00:00Z", next: "2026-03-31T09:00:00Z", found: true},
+		{name: "next wrapping weekdays", exp: "Fri..Mon 22:00 UTC", from: "2026-10-13T00:00:00Z", next: "2026-10-16T22:00:00Z", found: true},
+		{name: "next wrapping weekdays after sunday", exp: "Fri..Mon 22:00 UTC", from: "2026-10-18T23:00:00Z", next: "2026-10-19T22:00:00Z", found: true},
+		{name: "next repeated weekday", exp: "Mon/2 12:00 UTC", from: "2026-10-13T13:00:00Z", next: "2026-10-14T12:00:00Z", found: true},
+		{name: "next repeated weekday range", exp: "Mon..Tue/3 12:00 UTC", from: "2026-10-16T13:00:00Z", next: "2026-10-18T12:00:00Z", found: true},
+		{name: "next iso week 53", exp: "W53 *-*-* UTC", from: "2020-12-01T00:00:00Z", next: "2020-12-28T00:00:00Z", found: true},
+		{name: "next iso week 1 of the next year", exp: "W01 *-*-* UTC", from: "2024-12-01T00:00:00Z", next: "2024-12-30T00:00:00Z", found: true},
+		{name: "next iso week 1 after a week 53", exp: "W01 UTC", from: "2026-12-20T00:00:00Z", next: "2027-01-04T00:00:00Z", found: true},
+		{name: "next every other iso week", exp: "W02/2 *-*-* 09:00 UTC", from: "2026-01-01T00:00:00Z", next: "2026-01-05T09:00:00Z", found: true},
+		{name: "next first day of the year on monday", exp: "Mon D1 12:00 UTC", next: "2007-01-01T12:00:00Z", found: true},
+		{name: "next last day of a leap year", exp: "D366 UTC", from: "2025-01-01T00:00:00Z", next: "2028-12-31T00:00:00Z", found: true},
 	} {
 		t.Run(c.name, func(t *testing.T) {
 			exp, err := Parse(c.exp)
@@ -256,7 +418,15 @@ func TestExpression_Next(t *testing.T) {
 				t.Fatalf("unexpected error parsing next time: %s", err)
 			}
 
-			out, ok := exp.Next(current)
+			var from = current
+			if c.from != "" {
+				from, err = time.Parse(time.RFC3339, c.from)
+				if err != nil {
+					t.Fatalf("unexpected error parsing from time: %s", err)
+				}
+			}
+
+			out, ok := exp.Next(from)
 			if ok != c.found {
 				t.Fatalf("unexpected found output: wanted %v, got %v", c.found, ok)
 			}
@@ -272,6 +442,315 @@ func TestExpression_Next(t *testing.T) {
 	}
 }
 
+func TestExpression_Prev(t *testing.T) {
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	type Case struct {
+		name  string
+		exp   string
+		from  string
+		prev  string
+		found bool
+	}
+
+	for _, c := range []Case{
+		{name: "prev year", exp: "*-01-01 00:00:00 UTC", prev: "2006-01-01T00:00:00Z", found: true},
+		{name: "prev month", exp: "*-*-02 00:00:00 UTC", prev: "2006-01-02T00:00:00Z", found: true},
+		{name: "prev day", exp: "*-*-* 16:00:00 UTC", prev: "2006-01-01T16:00:00Z", found: true},
+		{name: "prev same time", exp: "*-*-* 15:04:05 UTC", prev: "2006-01-01T15:04:05Z", found: true},
+		{name: "prev last friday", exp: "Fri#-1 12:00 UTC", prev: "2005-12-30T12:00:00Z", found: true},
+		{name: "prev first monday", exp: "Mon#1 12:00 UTC", prev: "2006-01-02T12:00:00Z", found: true},
+		{name: "prev nearest weekday", exp: "*-*-15W 09:00 UTC", from: "2026-08-20T00:00:00Z", prev: "2026-08-14T09:00:00Z", found: true},
+		{name: "prev nearest weekday of the last day", exp: "*-*~01W 09:00 UTC", from: "2026-06-01T00:00:00Z", prev: "2026-05-29T09:00:00Z", found: true},
+		{name: "prev iso week 53", exp: "W53 *-*-* UTC", from: "2020-12-01T00:00:00Z", prev: "2016-01-03T00:00:00Z", found: true},
+		{name: "prev iso week 1", exp: "W01 UTC", from: "2026-12-20T00:00:00Z", prev: "2026-01-04T00:00:00Z", found: true},
+		{name: "prev last day of a leap year", exp: "D366 UTC", from: "2025-01-01T00:00:00Z", prev: "2024-12-31T00:00:00Z", found: true},
+		{name: "prev time skipped by daylight saving time", exp: "*-*-* 02:30 Europe/Paris", from: "2026-03-29T03:30:00+02:00", prev: "2026-03-28T02:30:00+01:00", found: true},
+		{name: "no prev date", exp: "2007-*-* 00:00:00 UTC", found: false},
+		{name: "prev monday", exp: "Mon 00:00:00 UTC", prev: "2006-01-02T00:00:00Z", found: true},
+		{name: "prev sunday", exp: "Sun 00:00:00 UTC", prev: "2006-01-01T00:00:00Z", found: true},
+		{name: "prev tuesday", exp: "Tue 00:00:00 UTC", prev: "2005-12-27T00:00:00Z", found: true},
+		{name: "prev first friday", exp: "Fri *-*-1..7 00:00:00 UTC", prev: "2005-12-02T00:00:00Z", found: true},
+		{name: "prev fortnight", exp: "*-*-1,15 00:00:00 UTC", prev: "2006-01-01T00:00:00Z", found: true},
+		{name: "prev ten min", exp: "*-*-* *:00/10:00 UTC", prev: "2006-01-02T15:00:00Z", found: true},
+		{name: "prev leap day", exp: "*-02-29 00:00:00 UTC", prev: "2004-02-29T00:00:00Z", found: true},
+		{name: "prev last day", exp: "*-*~1 UTC", prev: "2005-12-31T00:00:00Z", found: true},
+		{name: "prev last day of february", exp: "*-02~1 UTC", from: "2005-01-01T00:00:00Z", prev: "2004-02-29T00:00:00Z", found: true},
+		{name: "prev last monday of may", exp: "Mon *-05~07/1 UTC", prev: "2005-05-30T00:00:00Z", found: true},
+		{name: "prev quarter second", exp: "*:*:0/0.25 UTC", prev: "2006-01-02T15:04:04.75Z", found: true},
+		{name: "prev in timezone", exp: "*-*-* 00:00:00 Europe/Paris", prev: "2006-01-01T23:00:00Z", found: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			exp, err := Parse(c.exp)
+			if err != nil {
+				t.Fatalf("unexpected error parsing expression: %s", err)
+			}
+
+			var from = current
+			if c.from != "" {
+				from, err = time.Parse(time.RFC3339, c.from)
+				if err != nil {
+					t.Fatalf("unexpected error parsing from time: %s", err)
+				}
+			}
+
+			out, ok := exp.Prev(from)
+			if ok != c.found {
+				t.Fatalf("unexpected found output: wanted %v, got %v", c.found, ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			prev, err := time.Parse(time.RFC3339, c.prev)
+			if err != nil {
+				t.Fatalf("unexpected error parsing prev time: %s", err)
+			}
+
+			if !prev.Equal(out) {
+				t.Fatalf("unexpected time output: wanted %v, got %v", prev, out)
+			}
+		})
+	}
+}
+
+func TestExpression_Matches(t *testing.T) {
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	type Case struct {
+		name  string
+		exp   string
+		match bool
+	}
+
+	for _, c := range []Case{
+		{name: "exact match", exp: "Mon 2006-01-02 15:04:05 UTC", match: true},
+		{name: "wildcards", exp: "*-*-* *:*:* UTC", match: true},
+		{name: "repeat", exp: "*-*-* *:00/4:00/5 UTC", match: true},
+		{name: "other weekday", exp: "Tue *-*-* *:*:* UTC", match: false},
+		{name: "other second", exp: "*-*-* 15:04:06 UTC", match: false},
+		{name: "other timezone", exp: "*-*-* 15:04:05 Europe/Paris", match: false},
+		{name: "converted timezone", exp: "*-*-* 16:04:05 Europe/Paris", match: true},
+		{name: "end of month", exp: "*-*~31 15:04:05 UTC", match: false},
+		{name: "sub-second", exp: "*-*-* 15:04:05.5 UTC", match: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if match := MustParse(c.exp).Matches(current); match != c.match {
+				t.Errorf("unexpected output: wanted %v, got %v", c.match, match)
+			}
+		})
+	}
+}
+
+func TestExpression_IsSatisfiable(t *testing.T) {
+	type Case struct {
+		name        string
+		exp         string
+		satisfiable bool
+	}
+
+	for _, c := range []Case{
+		{name: "wildcards", exp: "*-*-* *:*:* UTC", satisfiable: true},
+		{name: "exact date", exp: "Mon 2024-01-01 UTC", satisfiable: true},
+		{name: "other weekday", exp: "Mon 2024-01-02 UTC", satisfiable: false},
+		{name: "leap day", exp: "*-02-29 UTC", satisfiable: true},
+		{name: "leap day of a common year", exp: "Fri 2023-02-29 UTC", satisfiable: false},
+		{name: "leap day on a weekday", exp: "Mon *-02-29 UTC", satisfiable: true},
+		{name: "leap day in common years", exp: "2021..2023-02-29 UTC", satisfiable: false},
+		{name: "end of month", exp: "Sun 2024-*~01 UTC", satisfiable: true},
+		{name: "weekdays of a week", exp: "Sat,Sun 2024-01-01..05 UTC", satisfiable: false},
+		{name: "repeated years", exp: "Mon 2024/4-01-02 UTC", satisfiable: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			exp := MustParse(c.exp)
+			if satisfiable := exp.IsSatisfiable(); satisfiable != c.satisfiable {
+				t.Errorf("unexpected output: wanted %v, got %v", c.satisfiable, satisfiable)
+			}
+
+			// The result must be consistent with the occurrences of the
+			// expression.
+			_, ok := exp.Next(time.Date(MinYears, 01, 01, 0, 0, 0, 0, time.UTC).Add(-time.Second))
+			if ok != c.satisfiable {
+				t.Errorf("inconsistent with Next: got %v", ok)
+			}
+		})
+	}
+}
+
+func TestExpression_Normalize(t *testing.T) {
+	type Case struct {
+		name     string
+		exp      string
+		expected string
+	}
+
+	for _, c := range []Case{
+		{name: "unordered weekdays", exp: "Sat,Thu,Mon..Wed,Sat..Sun UTC", expected: "Mon..Thu,Sat,Sun *-*-* 00:00:00 UTC"},
+		{name: "all weekdays", exp: "Mon..Fri,Sat,Sun UTC", expected: "*-*-* 00:00:00 UTC"},
+		{name: "full range", exp: "0..23:00 UTC", expected: "*-*-* *:00:00 UTC"},
+		{name: "progression", exp: "*:0,15,30,45 UTC", expected: "*-*-* *:00/15:00 UTC"},
+		{name: "consecutive values", exp: "*-*-3,1,2 UTC", expected: "*-*-01..03 00:00:00 UTC"},
+		{name: "range to the end", exp: "2024..2199-*-* UTC", expected: "2024..2199-*-* 00:00:00 UTC"},
+		{name: "overlapping repeats", exp: "*-*-* *:0/10,0/20 UTC", expected: "*-*-* *:00/10:00 UTC"},
+		{name: "microsecond repeat", exp: "*:*:0/0.000001,0/0.000001 UTC", expected: "*-*-* *:*:00/0.000001 UTC"},
+		{name: "interleaved repeats", exp: "*:*:0/0.001,0.0005 UTC", expected: "*-*-* *:*:00,00.0005,00.001/0.001 UTC"},
+		{name: "from end", exp: "*-*~03,01,02,10 UTC", expected: "*-*~01..03,10 00:00:00 UTC"},
+		{name: "from end repeat", exp: "*-*~07,05,03,01 UTC", expected: "*-*~07/2 00:00:00 UTC"},
+		{name: "occurrences", exp: "Fri#L,Mon#3,Tue,Mon#1 UTC", expected: "Mon#1,Mon#3,Tue,Fri#-1 *-*-* 00:00:00 UTC"},
+		{name: "all occurrences", exp: "Wed#1,Wed#2,Wed#3,Wed#4,Wed#-1 UTC", expected: "Wed *-*-* 00:00:00 UTC"},
+		{name: "redundant occurrence", exp: "Sun#-5,Sun#1 UTC", expected: "Sun#1 *-*-* 00:00:00 UTC"},
+		{name: "nearest weekdays", exp: "*-*-15W,01W,15w,03 UTC", expected: "*-*-03,01W,15W 00:00:00 UTC"},
+		{name: "wrapping weekdays", exp: "Fri..Mon 22:00 UTC", expected: "Mon,Fri..Sun *-*-* 22:00:00 UTC"},
+		{name: "wrapping weekdays to all", exp: "Thu..Wed UTC", expected: "*-*-* 00:00:00 UTC"},
+		{name: "repeated weekdays", exp: "Sun,Fri,Wed,Mon UTC", expected: "Mon/2 *-*-* 00:00:00 UTC"},
+		{name: "repeated weekday range", exp: "Mon..Tue/5 UTC", expected: "Mon,Tue,Sat,Sun *-*-* 00:00:00 UTC"},
+		{name: "repeated wrapping weekdays", exp: "Fri..Mon/2 UTC", expected: "Mon..Wed,Fri..Sun *-*-* 00:00:00 UTC"},
+		{name: "iso weeks and days of the year", exp: "D001 W1 daily", expected: "W01 D01 *-*-* 00:00:00"},
+		{name: "all iso weeks and days of the year", exp: "W1..53 D1..366 UTC", expected: "*-*-* 00:00:00 UTC"},
+		{name: "overlapping days of the year", exp: "D3,1..2,2 UTC", expected: "D01..03 *-*-* 00:00:00 UTC"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			exp := MustParse(c.exp).Normalize()
+			if exp.String() != c.expected {
+				t.Errorf("unexpected output: wanted %q, got %q", c.expected, exp.String())
+			}
+		})
+	}
+}
+
+func TestExpression_Microseconds(t *testing.T) {
+	// The values of the seconds must not be listed to be compiled,
+	// normalized or described.
+	var start = time.Now()
+	exp := MustParse("*:*:0/0.000001 UTC")
+	out := exp.String()
+	description := exp.Describe(English)
+	equal := exp.Equal(MustParse("*:*:00/0.000001 UTC"))
+	next, _ := exp.Next(time.Date(2020, 1, 1, 0, 0, 59, 999999000, time.UTC))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unexpected duration: got %v", elapsed)
+	}
+
+	if out != "*-*-* *:*:00/0.000001 UTC" {
+		t.Errorf("unexpected output: got %q", out)
+	}
+	if description != "every 0.000001 seconds, UTC" {
+		t.Errorf("unexpected description: got %q", description)
+	}
+	if !equal {
+		t.Errorf("unexpected difference")
+	}
+	if want := time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("unexpected next: want %v, got %v", want, next)
+	}
+}
+
+func TestExpression_Equal(t *testing.T) {
+	type Case struct {
+		name  string
+		a, b  string
+		equal bool
+	}
+
+	for _, c := range []Case{
+		{name: "identical", a: "Mon *-*-* 12:00 UTC", b: "Mon *-*-* 12:00 UTC", equal: true},
+		{name: "full range", a: "*-*-* 0..23:00 UTC", b: "*-*-* *:00 UTC", equal: true},
+		{name: "all weekdays", a: "Mon..Sun *-*-* UTC", b: "*-*-* UTC", equal: true},
+		{name: "repeat", a: "*:0/15 UTC", b: "*:0,15,30,45 UTC", equal: true},
+		{name: "unordered", a: "Sat,Mon *-*-* UTC", b: "Mon,Sat *-*-* UTC", equal: true},
+		{name: "all days from end", a: "*-*~01..31 UTC", b: "*-*-* UTC", equal: true},
+		{name: "sub-seconds", a: "*:*:0/0.5 UTC", b: "*:*:0/1,0.5/1 UTC", equal: true},
+		{name: "different values", a: "*-*-* 12:00 UTC", b: "*-*-* 13:00 UTC", equal: false},
+		{name: "different days", a: "*-*-31 UTC", b: "*-*~01 UTC", equal: false},
+		{name: "different timezones", a: "*-*-* UTC", b: "*-*-* Europe/Paris", equal: false},
+		{name: "different seconds", a: "*:*:0/0.5 UTC", b: "*:*:0 UTC", equal: false},
+		{name: "all occurrences", a: "Tue#1,Tue#2,Tue#3,Tue#4,Tue#5 UTC", b: "Tue UTC", equal: true},
+		{name: "last occurrence", a: "Fri#L UTC", b: "Fri#-1 UTC", equal: true},
+		{name: "fifth occurrence in the last", a: "Fri#-1,Fri#5 UTC", b: "Fri#-1 UTC", equal: true},
+		{name: "different occurrences", a: "Fri#-1 UTC", b: "Fri#4 UTC", equal: false},
+		{name: "nearest weekday", a: "*-*-15W UTC", b: "*-*-15 UTC", equal: false},
+		{name: "wrapping weekdays", a: "Fri..Mon UTC", b: "Mon,Fri..Sun UTC", equal: true},
+		{name: "repeated weekdays", a: "Tue/2 UTC", b: "Tue,Thu,Sat UTC", equal: true},
+		{name: "iso weeks", a: "W1,2 UTC", b: "W01..02 UTC", equal: true},
+		{name: "all iso weeks", a: "W1..53 UTC", b: "*-*-* UTC", equal: true},
+		{name: "different days of the year", a: "D1 UTC", b: "D2 UTC", equal: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := MustParse(c.a), MustParse(c.b)
+			if out := a.Equal(b); out != c.equal {
+				t.Errorf("unexpected output: wanted %v, got %v", c.equal, out)
+			}
+
+			if out := b.Equal(a); out != c.equal {
+				t.Errorf("unexpected output in reverse: wanted %v, got %v", c.equal, out)
+			}
+
+			if out := a.Hash() == b.Hash(); out != c.equal {
+				t.Errorf("unexpected hash comparison: wanted %v, got %v", c.equal, out)
+			}
+
+			// Equal expressions are marshaled the same way, unless
+			// they count the days differently.
+			if c.equal && c.name != "all days from end" && a.String() != b.String() {
+				t.Errorf("unexpected marshaling: %q and %q", a.String(), b.String())
+			}
+		})
+	}
+}
+
+func TestExpression_Occurrences(t *testing.T) {
+	var (
+		from = time.Date(2006, 01, 02, 0, 0, 0, 0, time.UTC)
+		to   = time.Date(2006, 01, 03, 0, 0, 0, 0, time.UTC)
+	)
+
+	type Case struct {
+		name string
+		exp  string
+		out  []time.Time
+	}
+
+	for _, c := range []Case{
+		{name: "include from", exp: "*-*-* 00,12:00 UTC", out: []time.Time{
+			time.Date(2006, 01, 02, 0, 0, 0, 0, time.UTC),
+			time.Date(2006, 01, 02, 12, 0, 0, 0, time.UTC),
+		}},
+		{name: "exclude to", exp: "*-*-* 00:00 UTC", out: []time.Time{
+			time.Date(2006, 01, 02, 0, 0, 0, 0, time.UTC),
+		}},
+		{name: "no occurrence", exp: "2005-*-* UTC", out: nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out := slices.Collect(MustParse(c.exp).Occurrences(from, to))
+			if !reflect.DeepEqual(c.out, out) {
+				t.Errorf("unexpected output: wanted %v, got %v", c.out, out)
+			}
+		})
+	}
+}
+
+func TestExpression_NextAllocs(t *testing.T) {
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	for _, raw := range []string{
+		"Mon *-*-1..7 00:00:00 UTC",
+		"*-*~1 12:00 Europe/Paris",
+		"*:*:0/0.25 UTC",
+	} {
+		exp := MustParse(raw)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = exp.Next(current)
+			_, _ = exp.Prev(current)
+			_ = exp.Matches(current)
+		})
+		if allocs != 0 {
+			t.Errorf("unexpected allocations for %s: got %v", raw, allocs)
+		}
+	}
+}
+
 func BenchmarkExpression_Next(b *testing.B) {
 	var start = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
 	// Next is more or less efficient depending on the current day, so to
@@ -301,6 +780,7 @@ func BenchmarkExpression_Next(b *testing.B) {
 			if err != nil {
 				b.Fatalf("unexpected error parsing expression: %s", err)
 			}
+			b.ReportAllocs()
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
@@ -0,0 +1,359 @@
+package zcalendar
+
+import (
+	"iter"
+	"time"
+)
+
+// A HolidayCalendar tells which days are holidays. The days which aren't
+// holidays are business days, so calendars may report the weekends as
+// holidays. The holiday package provides calendars holding holidays in memory,
+// loaded from rules or files.
+type HolidayCalendar interface {
+	// IsHoliday returns whether the day of d, in the location of d, is a
+	// holiday.
+	IsHoliday(d time.Time) bool
+}
+
+// A HolidayFunc is a HolidayCalendar telling whether the day of d is a holiday
+// with a function.
+type HolidayFunc func(d time.Time) bool
+
+// IsHoliday implements the HolidayCalendar interface.
+func (f HolidayFunc) IsHoliday(d time.Time) bool {
+	return f(d)
+}
+
+// A HolidayPolicy tells what happens to the points in time of a schedule that
+// fall on holidays.
+type HolidayPolicy int
+
+// The policies for the holidays.
+const (
+	// SkipHolidays drops the points in time falling on holidays.
+	SkipHolidays HolidayPolicy = iota
+
+	// NextBusinessDay moves the points in time falling on holidays to the
+	// same time of the next business day.
+	NextBusinessDay
+
+	// PreviousBusinessDay moves the points in time falling on holidays to
+	// the same time of the previous business day.
+	PreviousBusinessDay
+)
+
+// maxHolidays is the maximum number of consecutive holidays over which a point
+// in time is moved to a business day.
+const maxHolidays = 366
+
+// A HolidaySchedule is a schedule whose points in time falling on the holidays
+// of a calendar are skipped or moved to a business day, depending on the
+// policy. The holidays are checked in the timezone of the expressions. The
+// points in time moved to a day that already has them are only returned once.
+type HolidaySchedule struct {
+	Schedule Schedule
+	Calendar HolidayCalendar
+	Policy   HolidayPolicy
+}
+
+// WithHolidays returns the schedule with the holidays of the calendar handled
+// according to the policy.
+func (s Schedule) WithHolidays(c HolidayCalendar, p HolidayPolicy) HolidaySchedule {
+	return HolidaySchedule{Schedule: s, Calendar: c, Policy: p}
+}
+
+// WithHolidays returns a schedule made of the expression, with the holidays of
+// the calendar handled according to the policy.
+func (e Expression) WithHolidays(c HolidayCalendar, p HolidayPolicy) HolidaySchedule {
+	return Schedule{e}.WithHolidays(c, p)
+}
+
+// Matches returns whether the point in time is one of the schedule.
+func (s HolidaySchedule) Matches(d time.Time) bool {
+	if s.Policy == SkipHolidays {
+		return s.Schedule.Matches(d) && !s.Calendar.IsHoliday(d)
+	}
+
+	n, ok := s.Next(d.Add(-time.Microsecond))
+	return ok && n.Equal(d)
+}
+
+// Next returns the first point in time of the schedule that is strictly after
+// d.
+func (s HolidaySchedule) Next(d time.Time) (n time.Time, ok bool) {
+	// The points in time of the holidays right before d may be moved
+	// after it.
+	var (
+		cursor = d
+		run    holidayRun
+		single = s.singleTimezone()
+		limit  time.Time
+	)
+	if s.Policy == NextBusinessDay {
+		cursor = s.limit(d, -1, &run).Add(-time.Microsecond)
+	}
+
+	for {
+		occurrence, found := s.Schedule.Next(cursor)
+		if !found || (ok && !occurrence.Before(limit)) {
+			return n, ok
+		}
+
+		moved, kept := s.move(occurrence, &run)
+		if kept && moved.After(d) && (!ok || moved.Before(n)) {
+			n, ok = moved, true
+			limit = s.limit(n, 1, &run)
+		}
+
+		// The points in time of a business day are skipped, except
+		// the first one after d. Those of a holiday are moved to the
+		// same day, so their order is kept and at most one of them is
+		// needed: the first one moved after d.
+		var (
+			year, month, day = occurrence.Date()
+			end              = time.Date(year, month, day+1, 0, 0, 0, 0, occurrence.Location()).Add(-time.Microsecond)
+		)
+		switch {
+		case !single:
+			cursor = occurrence
+		case kept && moved.Equal(occurrence) && occurrence.After(d):
+			cursor = end
+		case kept && moved.Equal(occurrence):
+			cursor = later(occurrence, earlier(d, end))
+		case kept && !moved.After(d) && sameDay(moved, d):
+			d := d.In(occurrence.Location())
+			cursor = later(occurrence, time.Date(year, month, day, d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), occurrence.Location()))
+		default:
+			cursor = end
+		}
+	}
+}
+
+// Prev returns the last point in time of the schedule that is strictly before
+// d.
+func (s HolidaySchedule) Prev(d time.Time) (p time.Time, ok bool) {
+	// The points in time of the holidays right after d may be moved
+	// before it.
+	var (
+		cursor = d
+		run    holidayRun
+		single = s.singleTimezone()
+		limit  time.Time
+	)
+	if s.Policy == PreviousBusinessDay {
+		cursor = s.limit(d, 1, &run)
+	}
+
+	for {
+		occurrence, found := s.Schedule.Prev(cursor)
+		if !found || (ok && occurrence.Before(limit)) {
+			return p, ok
+		}
+
+		moved, kept := s.move(occurrence, &run)
+		if kept && moved.Before(d) && (!ok || moved.After(p)) {
+			p, ok = moved, true
+			limit = s.limit(p, -1, &run)
+		}
+
+		// As in Next, the points in time of a business day are
+		// skipped, except the last one before d, and at most one point
+		// in time of a holiday is needed: the last one moved before d.
+		var (
+			year, month, day = occurrence.Date()
+			start            = time.Date(year, month, day, 0, 0, 0, 0, occurrence.Location())
+		)
+		switch {
+		case !single:
+			cursor = occurrence
+		case kept && moved.Equal(occurrence) && occurrence.Before(d):
+			cursor = start
+		case kept && moved.Equal(occurrence):
+			cursor = earlier(occurrence, later(d, start))
+		case kept && !moved.Before(d) && sameDay(moved, d):
+			d := d.In(occurrence.Location())
+			cursor = earlier(occurrence, time.Date(year, month, day, d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), occurrence.Location()))
+		default:
+			cursor = start
+		}
+	}
+}
+
+// singleTimezone returns whether all the expressions of the schedule have the
+// same timezone, in which case its points in time can be skipped by days.
+func (s HolidaySchedule) singleTimezone() bool {
+	for _, e := range s.Schedule {
+		if e.timezone.String() != s.Schedule[0].timezone.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// Occurrences returns an iterator over the points in time of the schedule
+// between from, included, and to, excluded.
+func (s HolidaySchedule) Occurrences(from, to time.Time) iter.Seq[time.Time] {
+	return occurrences(s.Next, from, to)
+}
+
+// move returns the point in time to which a point in time of the schedule is
+// moved, or false if it is skipped.
+func (s HolidaySchedule) move(occurrence time.Time, run *holidayRun) (time.Time, bool) {
+	if !s.isHoliday(occurrence, run) {
+		return occurrence, true
+	}
+
+	var step int
+	switch s.Policy {
+	case NextBusinessDay:
+		step = 1
+	case PreviousBusinessDay:
+		step = -1
+	default:
+		return time.Time{}, false
+	}
+
+	day, found := s.businessDay(occurrence, step, run)
+	if !found {
+		return time.Time{}, false
+	}
+
+	var year, month, dd = day.Date()
+	return time.Date(year, month, dd, occurrence.Hour(), occurrence.Minute(), occurrence.Second(), occurrence.Nanosecond(), occurrence.Location()), true
+}
+
+// limit returns the point in time from which, in the direction of step, the
+// points in time of the schedule can't be moved back to n or beyond it: those
+// from the limit can't be moved before n if step is 1, and those before the
+// limit can't be moved after n if step is -1. Only the points in time of
+// holidays are moved back, to a business day, so the limit is the midnight of
+// the first business day after the day of n in the timezone of each
+// expression if step is 1, or the end of the last one before it if step is -1.
+func (s HolidaySchedule) limit(n time.Time, step int, run *holidayRun) (limit time.Time) {
+	var backward = (step == 1 && s.Policy == PreviousBusinessDay) || (step == -1 && s.Policy == NextBusinessDay)
+	if !backward {
+		return n
+	}
+
+	for _, e := range s.Schedule {
+		var bound time.Time
+		day, found := s.businessDay(n.In(e.timezone), step, run)
+		switch {
+		case !found:
+			bound = n.AddDate(0, 0, step*(maxHolidays+1))
+		case step == 1:
+			bound = day
+		default:
+			bound = day.AddDate(0, 0, 1)
+		}
+
+		if limit.IsZero() || (step == 1 && bound.After(limit)) || (step == -1 && bound.Before(limit)) {
+			limit = bound
+		}
+	}
+	return limit
+}
+
+// businessDay returns the midnight of the nearest business day after the day
+// of d if step is 1, or before it if step is -1, or false if there is none
+// within maxHolidays days. The holidays of the run aren't checked again, and
+// those found are added to it.
+func (s HolidaySchedule) businessDay(d time.Time, step int, run *holidayRun) (time.Time, bool) {
+	var (
+		year, month, day = d.Date()
+		found            = -1
+	)
+	for i := 1; i <= maxHolidays; i++ {
+		var midnight = time.Date(year, month, day+i*step, 0, 0, 0, 0, d.Location())
+		if run.contains(midnight) {
+			// Continue from the end of the run.
+			i += int(run.edge(step).Sub(midnight).Abs().Round(time.Hour) / (24 * time.Hour))
+			continue
+		}
+
+		if !s.Calendar.IsHoliday(midnight) {
+			found = i
+			break
+		}
+	}
+
+	var last = maxHolidays
+	if found != -1 {
+		last = found - 1
+	}
+	if last >= 1 {
+		run.add(time.Date(year, month, day+step, 0, 0, 0, 0, d.Location()), time.Date(year, month, day+last*step, 0, 0, 0, 0, d.Location()))
+	}
+
+	if found == -1 {
+		return time.Time{}, false
+	}
+	return time.Date(year, month, day+found*step, 0, 0, 0, 0, d.Location()), true
+}
+
+// isHoliday returns whether the day of d is a holiday, using the run if it
+// contains it.
+func (s HolidaySchedule) isHoliday(d time.Time, run *holidayRun) bool {
+	var year, month, day = d.Date()
+	return run.contains(time.Date(year, month, day, 0, 0, 0, 0, d.Location())) || s.Calendar.IsHoliday(d)
+}
+
+// A holidayRun is a range of consecutive holidays, from the midnight of the
+// first one to the midnight of the last one, found while looking for business
+// days. It prevents the holidays from being checked for each point in time of
+// a long run of holidays.
+type holidayRun struct {
+	first, last time.Time
+}
+
+// contains returns whether the midnight is one of the run.
+func (r *holidayRun) contains(midnight time.Time) bool {
+	return !r.first.IsZero() && midnight.Location() == r.first.Location() && !midnight.Before(r.first) && !midnight.After(r.last)
+}
+
+// edge returns the midnight of the last holiday of the run if step is 1, or
+// of the first one if step is -1.
+func (r *holidayRun) edge(step int) time.Time {
+	if step == 1 {
+		return r.last
+	}
+	return r.first
+}
+
+// add adds the holidays between both midnights, in any order, to the run. The
+// run is replaced if they aren't adjacent to it.
+func (r *holidayRun) add(a, b time.Time) {
+	var first, last = earlier(a, b), later(a, b)
+	switch {
+	case r.first.IsZero() || first.Location() != r.first.Location() || first.After(r.last.AddDate(0, 0, 1)) || last.Before(r.first.AddDate(0, 0, -1)):
+		r.first, r.last = first, last
+	default:
+		r.first, r.last = earlier(first, r.first), later(last, r.last)
+	}
+}
+
+// sameDay returns whether both points in time are on the same day, in the
+// location of a.
+func sameDay(a, b time.Time) bool {
+	var (
+		y1, m1, d1 = a.Date()
+		y2, m2, d2 = b.In(a.Location()).Date()
+	)
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// earlier returns the earliest of both points in time.
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// later returns the latest of both points in time.
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
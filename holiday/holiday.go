@@ -0,0 +1,151 @@
+// Package holiday provides the holiday calendars used by the schedules of
+// zcalendar to skip the holidays or move their points in time to business
+// days. The calendars hold their holidays in memory, added one by one, from
+// rules or loaded from CSV and iCalendar files.
+package holiday
+
+import (
+	"slices"
+	"time"
+
+	"github.com/synthesio/zcalendar"
+)
+
+// A Rule gives the days of a holiday over the years.
+type Rule struct {
+	Name string
+
+	// matches returns whether the day starting at midnight is the
+	// holiday.
+	matches func(midnight time.Time) bool
+}
+
+// Fixed returns the rule of a holiday on the same day every year, such
+// as Christmas on December 25.
+func Fixed(name string, month time.Month, day int) Rule {
+	return Rule{Name: name, matches: func(midnight time.Time) bool {
+		_, m, d := midnight.Date()
+		return m == month && d == day
+	}}
+}
+
+// Easter returns the rule of a holiday a number of days after the
+// Western Easter Sunday, or before it if offset is negative, such as Easter
+// Monday with an offset of 1 or Good Friday with an offset of -2. The holiday
+// must be in the same year as Easter.
+func Easter(name string, offset int) Rule {
+	return Rule{Name: name, matches: func(midnight time.Time) bool {
+		year, m, d := midnight.Date()
+		month, day := easter(year)
+		_, month, day = time.Date(year, month, day+offset, 0, 0, 0, 0, time.UTC).Date()
+		return m == month && d == day
+	}}
+}
+
+// FromSchedule returns the rule of a holiday on the days during which the
+// schedule has a point in time, in the location of the days.
+func FromSchedule(name string, s zcalendar.Schedule) Rule {
+	return Rule{Name: name, matches: func(midnight time.Time) bool {
+		year, month, day := midnight.Date()
+		n, ok := s.Next(midnight.Add(-time.Microsecond))
+		return ok && n.Before(time.Date(year, month, day+1, 0, 0, 0, 0, midnight.Location()))
+	}}
+}
+
+// floating returns the rule matching the days whose date is matched by the
+// rule in UTC, so that it is matched on dates whatever the location of the
+// days, as the days of a rule built from UTC points in time would otherwise
+// be shifted in the other locations.
+func floating(r Rule) Rule {
+	return Rule{Name: r.Name, matches: func(midnight time.Time) bool {
+		year, month, day := midnight.Date()
+		return r.matches(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+	}}
+}
+
+// easter returns the date of the Western Easter Sunday of the year, using the
+// anonymous Gregorian algorithm.
+func easter(year int) (month time.Month, day int) {
+	var (
+		a = year % 19
+		b = year / 100
+		c = year % 100
+		d = b / 4
+		e = b % 4
+		f = (b + 8) / 25
+		g = (b - f + 1) / 3
+		h = (19*a + b - d - g + 15) % 30
+		i = c / 4
+		k = c % 4
+		l = (32 + 2*e + 2*i - h - k) % 7
+		m = (a + 11*h + 22*l) / 451
+	)
+
+	return time.Month((h + l - 7*m + 114) / 31), (h+l-7*m+114)%31 + 1
+}
+
+// A date is a day of the calendar, regardless of the location.
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// A MemoryCalendar is a zcalendar.HolidayCalendar holding its holidays in memory, as
+// dates and rules. It must not be modified while it is used.
+type MemoryCalendar struct {
+	// Weekend lists the days of the week which are reported as holidays.
+	Weekend []time.Weekday
+
+	dates map[date]string
+	rules []Rule
+}
+
+// NewMemoryCalendar creates a calendar with the holidays of the rules.
+func NewMemoryCalendar(rules ...Rule) *MemoryCalendar {
+	return &MemoryCalendar{dates: make(map[date]string), rules: rules}
+}
+
+// Add adds a holiday on the day.
+func (c *MemoryCalendar) Add(year int, month time.Month, day int, name string) {
+	if c.dates == nil {
+		c.dates = make(map[date]string)
+	}
+
+	// Normalize the day, so holidays can be added across months.
+	year, month, day = time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Date()
+	c.dates[date{year, month, day}] = name
+}
+
+// AddRule adds the holidays of the rule.
+func (c *MemoryCalendar) AddRule(r Rule) {
+	c.rules = append(c.rules, r)
+}
+
+// Holiday returns the name of the holiday on the day of d, in the location of
+// d, and whether there is one. The weekends are named after their weekday.
+func (c *MemoryCalendar) Holiday(d time.Time) (name string, ok bool) {
+	year, month, day := d.Date()
+	if name, ok := c.dates[date{year, month, day}]; ok {
+		return name, true
+	}
+
+	var midnight = time.Date(year, month, day, 0, 0, 0, 0, d.Location())
+	for _, r := range c.rules {
+		if r.matches(midnight) {
+			return r.Name, true
+		}
+	}
+
+	if slices.Contains(c.Weekend, d.Weekday()) {
+		return d.Weekday().String(), true
+	}
+
+	return "", false
+}
+
+// IsHoliday implements the zcalendar.HolidayCalendar interface.
+func (c *MemoryCalendar) IsHoliday(d time.Time) bool {
+	_, ok := c.Holiday(d)
+	return ok
+}
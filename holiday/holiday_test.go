@@ -0,0 +1,60 @@
+package holiday
+
+import (
+	"testing"
+	"time"
+
+	"github.com/synthesio/zcalendar"
+)
+
+func TestEaster(t *testing.T) {
+	for year, expected := range map[int]time.Time{
+		2000: time.Date(2000, 04, 23, 0, 0, 0, 0, time.UTC),
+		2019: time.Date(2019, 04, 21, 0, 0, 0, 0, time.UTC),
+		2024: time.Date(2024, 03, 31, 0, 0, 0, 0, time.UTC),
+		2025: time.Date(2025, 04, 20, 0, 0, 0, 0, time.UTC),
+		2026: time.Date(2026, 04, 05, 0, 0, 0, 0, time.UTC),
+	} {
+		month, day := easter(year)
+		if month != expected.Month() || day != expected.Day() {
+			t.Errorf("unexpected date for %d: wanted %v, got %v %d", year, expected, month, day)
+		}
+	}
+}
+
+func TestMemoryCalendar_Holiday(t *testing.T) {
+	var calendar = NewMemoryCalendar(
+		Fixed("Christmas", time.December, 25),
+		Easter("Easter Monday", 1),
+		Easter("Good Friday", -2),
+		FromSchedule("First Monday", zcalendar.MustParseSchedule("Mon *-*-01..07 UTC")),
+	)
+	calendar.Add(2026, time.July, 14, "Bastille Day")
+	calendar.Weekend = []time.Weekday{time.Saturday, time.Sunday}
+
+	type Case struct {
+		day  time.Time
+		name string
+		ok   bool
+	}
+
+	for _, c := range []Case{
+		{day: time.Date(2026, 12, 25, 10, 0, 0, 0, time.UTC), name: "Christmas", ok: true},
+		{day: time.Date(2026, 04, 06, 10, 0, 0, 0, time.UTC), name: "Easter Monday", ok: true},
+		{day: time.Date(2026, 04, 03, 10, 0, 0, 0, time.UTC), name: "Good Friday", ok: true},
+		{day: time.Date(2026, 07, 14, 10, 0, 0, 0, time.UTC), name: "Bastille Day", ok: true},
+		{day: time.Date(2026, 06, 01, 10, 0, 0, 0, time.UTC), name: "First Monday", ok: true},
+		{day: time.Date(2026, 07, 11, 10, 0, 0, 0, time.UTC), name: "Saturday", ok: true},
+		{day: time.Date(2026, 07, 15, 10, 0, 0, 0, time.UTC), ok: false},
+		{day: time.Date(2027, 07, 14, 10, 0, 0, 0, time.UTC), ok: false},
+	} {
+		name, ok := calendar.Holiday(c.day)
+		if name != c.name || ok != c.ok {
+			t.Errorf("unexpected holiday on %v: wanted %q %v, got %q %v", c.day, c.name, c.ok, name, ok)
+		}
+
+		if calendar.IsHoliday(c.day) != c.ok {
+			t.Errorf("unexpected holiday check on %v", c.day)
+		}
+	}
+}
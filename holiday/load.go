@@ -0,0 +1,190 @@
+package holiday
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/synthesio/zcalendar"
+)
+
+// LoadCSV adds the holidays of a CSV file to the calendar. Each record has the
+// date of a holiday in the YYYY-MM-DD format, optionally followed by its name.
+// An optional header whose first field is "date" is skipped, as well as the
+// lines starting with a "#".
+func (c *MemoryCalendar) LoadCSV(r io.Reader) error {
+	var reader = csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	for index := 0; ; index++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading record %d: %w", index, err)
+		}
+
+		if index == 0 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+
+		day, err := time.Parse(time.DateOnly, strings.TrimSpace(record[0]))
+		if err != nil {
+			return fmt.Errorf("parsing record %d: %w", index, err)
+		}
+
+		var name string
+		if len(record) > 1 {
+			name = strings.TrimSpace(record[1])
+		}
+
+		c.Add(day.Year(), day.Month(), day.Day(), name)
+	}
+}
+
+// LoadICalendar adds the holidays of an RFC 5545 iCalendar file to the
+// calendar. Each event is a holiday named after its summary, from the day of
+// its start to the day before its end for the events lasting whole days. The
+// events repeated by a recurrence rule are added as rules matched on the dates
+// of the days, whatever their location, see zcalendar.ParseRRule for the
+// supported rules. The other properties of the events are ignored.
+func (c *MemoryCalendar) LoadICalendar(r io.Reader) error {
+	lines, err := unfoldICalendar(r)
+	if err != nil {
+		return fmt.Errorf("reading calendar: %w", err)
+	}
+
+	var (
+		event      map[string]icalProperty
+		eventIndex int
+	)
+
+	for _, line := range lines {
+		name, property, err := parseICalendarLine(line)
+		if err != nil {
+			return fmt.Errorf("parsing line %q: %w", line, err)
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(property.value, "VEVENT"):
+			event = make(map[string]icalProperty)
+		case name == "END" && strings.EqualFold(property.value, "VEVENT") && event != nil:
+			if err := c.addEvent(event); err != nil {
+				return fmt.Errorf("adding event %d: %w", eventIndex, err)
+			}
+			event = nil
+			eventIndex++
+		case event != nil:
+			event[name] = property
+		}
+	}
+
+	return nil
+}
+
+// An icalProperty is the value of a property of an iCalendar file, with its
+// parameters.
+type icalProperty struct {
+	params map[string]string
+	value  string
+}
+
+// unfoldICalendar returns the lines of an iCalendar file, joining the lines
+// that were folded because of their length.
+func unfoldICalendar(r io.Reader) (lines []string, err error) {
+	var scanner = bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+		case (line[0] == ' ' || line[0] == '\t') && len(lines) > 0:
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseICalendarLine returns the name and the property of a line of an
+// iCalendar file, such as "DTSTART;VALUE=DATE:20261225".
+func parseICalendarLine(line string) (name string, property icalProperty, err error) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", property, errors.New("missing value")
+	}
+
+	var params = strings.Split(head, ";")
+	property = icalProperty{params: make(map[string]string), value: value}
+	for _, param := range params[1:] {
+		key, v, _ := strings.Cut(param, "=")
+		property.params[strings.ToUpper(key)] = v
+	}
+
+	return strings.ToUpper(params[0]), property, nil
+}
+
+// parseICalendarDate returns the date of a DTSTART or DTEND property, and
+// whether it is a whole day.
+func parseICalendarDate(p icalProperty) (d time.Time, wholeDay bool, err error) {
+	if len(p.value) < 8 {
+		return d, false, fmt.Errorf("invalid date %q", p.value)
+	}
+
+	d, err = time.Parse("20060102", p.value[:8])
+	if err != nil {
+		return d, false, fmt.Errorf("invalid date %q", p.value)
+	}
+
+	return d, len(p.value) == 8 || strings.EqualFold(p.params["VALUE"], "DATE"), nil
+}
+
+// addEvent adds the holidays of an event of an iCalendar file.
+func (c *MemoryCalendar) addEvent(event map[string]icalProperty) error {
+	start, ok := event["DTSTART"]
+	if !ok {
+		return errors.New("missing start")
+	}
+
+	first, wholeDay, err := parseICalendarDate(start)
+	if err != nil {
+		return fmt.Errorf("parsing start: %w", err)
+	}
+
+	var name = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(event["SUMMARY"].value)
+
+	if rule, ok := event["RRULE"]; ok {
+		s, err := zcalendar.ParseRRule(rule.value, first)
+		if err != nil {
+			return fmt.Errorf("parsing rule: %w", err)
+		}
+
+		// The rule is built in UTC from the date of the start.
+		c.AddRule(floating(FromSchedule(name, s)))
+		return nil
+	}
+
+	// The end of the events lasting whole days is the day after them.
+	var last = first
+	if end, ok := event["DTEND"]; ok && wholeDay {
+		last, _, err = parseICalendarDate(end)
+		if err != nil {
+			return fmt.Errorf("parsing end: %w", err)
+		}
+		last = last.AddDate(0, 0, -1)
+	}
+
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		c.Add(d.Year(), d.Month(), d.Day(), name)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package holiday
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCalendar_LoadCSV(t *testing.T) {
+	var calendar = NewMemoryCalendar()
+
+	err := calendar.LoadCSV(strings.NewReader("date,name\n# Public holidays\n2026-12-25,Christmas\n2026-01-01, New Year's Day\n2026-05-01\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for day, expected := range map[time.Time]string{
+		time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC): "Christmas",
+		time.Date(2026, 01, 01, 0, 0, 0, 0, time.UTC): "New Year's Day",
+		time.Date(2026, 05, 01, 0, 0, 0, 0, time.UTC): "",
+	} {
+		if name, ok := calendar.Holiday(day); !ok || name != expected {
+			t.Errorf("unexpected holiday on %v: wanted %q, got %q %v", day, expected, name, ok)
+		}
+	}
+
+	err = calendar.LoadCSV(strings.NewReader("2026-13-01,Invalid\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "parsing record 0: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMemoryCalendar_LoadICalendar(t *testing.T) {
+	var calendar = NewMemoryCalendar()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = calendar.LoadICalendar(strings.NewReader(strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20261224",
+		"DTEND;VALUE=DATE:20261227",
+		"SUMMARY:Christmas\\, and the days",
+		"  around it",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20260714T080000Z",
+		"DTEND:20260714T120000Z",
+		"SUMMARY:Bastille Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20200501",
+		"RRULE:FREQ=YEARLY",
+		"SUMMARY:Labour Day",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type Case struct {
+		day  time.Time
+		name string
+		ok   bool
+	}
+
+	for _, c := range []Case{
+		{day: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), name: "Christmas, and the days around it", ok: true},
+		{day: time.Date(2026, 12, 26, 0, 0, 0, 0, time.UTC), name: "Christmas, and the days around it", ok: true},
+		{day: time.Date(2026, 12, 27, 0, 0, 0, 0, time.UTC), ok: false},
+		{day: time.Date(2026, 07, 14, 0, 0, 0, 0, time.UTC), name: "Bastille Day", ok: true},
+		{day: time.Date(2026, 07, 15, 0, 0, 0, 0, time.UTC), ok: false},
+		{day: time.Date(2031, 05, 01, 15, 0, 0, 0, time.UTC), name: "Labour Day", ok: true},
+		{day: time.Date(2019, 05, 01, 15, 0, 0, 0, time.UTC), ok: false},
+		{day: time.Date(2031, 05, 01, 0, 0, 0, 0, newYork), name: "Labour Day", ok: true},
+		{day: time.Date(2031, 04, 30, 23, 0, 0, 0, newYork), ok: false},
+		{day: time.Date(2031, 05, 01, 23, 0, 0, 0, time.FixedZone("UTC+14", 14*3600)), name: "Labour Day", ok: true},
+	} {
+		name, ok := calendar.Holiday(c.day)
+		if name != c.name || ok != c.ok {
+			t.Errorf("unexpected holiday on %v: wanted %q %v, got %q %v", c.day, c.name, c.ok, name, ok)
+		}
+	}
+
+	err = calendar.LoadICalendar(strings.NewReader("BEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\n"))
+	if err == nil || err.Error() != "adding event 0: missing start" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package zcalendar
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	// weekendCalendar has the weekends as holidays.
+	weekendCalendar = HolidayFunc(func(d time.Time) bool {
+		return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+	})
+
+	// christmasCalendar also has Christmas as a holiday.
+	christmasCalendar = HolidayFunc(func(d time.Time) bool {
+		return weekendCalendar(d) || (d.Month() == time.December && d.Day() == 25)
+	})
+)
+
+func TestHolidaySchedule_Next(t *testing.T) {
+	var calendar = christmasCalendar
+
+	type Case struct {
+		name   string
+		policy HolidayPolicy
+		from   time.Time
+		next   time.Time
+	}
+
+	for _, c := range []Case{
+		{name: "skip", policy: SkipHolidays, from: time.Date(2026, 12, 01, 0, 0, 0, 0, time.UTC), next: time.Date(2027, 01, 25, 12, 0, 0, 0, time.UTC)},
+		{name: "next business day", policy: NextBusinessDay, from: time.Date(2026, 12, 01, 0, 0, 0, 0, time.UTC), next: time.Date(2026, 12, 28, 12, 0, 0, 0, time.UTC)},
+		{name: "next business day after the holiday", policy: NextBusinessDay, from: time.Date(2026, 12, 26, 0, 0, 0, 0, time.UTC), next: time.Date(2026, 12, 28, 12, 0, 0, 0, time.UTC)},
+		{name: "previous business day", policy: PreviousBusinessDay, from: time.Date(2026, 12, 01, 0, 0, 0, 0, time.UTC), next: time.Date(2026, 12, 24, 12, 0, 0, 0, time.UTC)},
+		{name: "previous business day after it", policy: PreviousBusinessDay, from: time.Date(2026, 12, 24, 13, 0, 0, 0, time.UTC), next: time.Date(2027, 01, 25, 12, 0, 0, 0, time.UTC)},
+		{name: "business day", policy: NextBusinessDay, from: time.Date(2027, 01, 01, 0, 0, 0, 0, time.UTC), next: time.Date(2027, 01, 25, 12, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := MustParse("*-*-25 12:00 UTC").WithHolidays(calendar, c.policy)
+
+			next, ok := s.Next(c.from)
+			if !ok || !next.Equal(c.next) {
+				t.Errorf("unexpected output: wanted %v, got %v %v", c.next, next, ok)
+			}
+
+			// Prev must be consistent with Next.
+			prev, ok := s.Prev(next)
+			if ok && !prev.Before(c.from) && prev.Before(next) {
+				t.Errorf("unexpected previous point in time: %v", prev)
+			}
+
+			if !s.Matches(next) {
+				t.Errorf("expected %v to match", next)
+			}
+		})
+	}
+}
+
+func TestHolidaySchedule_Prev(t *testing.T) {
+	var calendar = christmasCalendar
+
+	type Case struct {
+		name   string
+		policy HolidayPolicy
+		from   time.Time
+		prev   time.Time
+	}
+
+	for _, c := range []Case{
+		{name: "skip", policy: SkipHolidays, from: time.Date(2027, 01, 01, 0, 0, 0, 0, time.UTC), prev: time.Date(2026, 11, 25, 12, 0, 0, 0, time.UTC)},
+		{name: "next business day", policy: NextBusinessDay, from: time.Date(2027, 01, 01, 0, 0, 0, 0, time.UTC), prev: time.Date(2026, 12, 28, 12, 0, 0, 0, time.UTC)},
+		{name: "previous business day", policy: PreviousBusinessDay, from: time.Date(2026, 12, 24, 13, 0, 0, 0, time.UTC), prev: time.Date(2026, 12, 24, 12, 0, 0, 0, time.UTC)},
+		{name: "previous business day before it", policy: PreviousBusinessDay, from: time.Date(2026, 12, 24, 11, 0, 0, 0, time.UTC), prev: time.Date(2026, 11, 25, 12, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := MustParse("*-*-25 12:00 UTC").WithHolidays(calendar, c.policy)
+
+			prev, ok := s.Prev(c.from)
+			if !ok || !prev.Equal(c.prev) {
+				t.Errorf("unexpected output: wanted %v, got %v %v", c.prev, prev, ok)
+			}
+		})
+	}
+}
+
+func TestHolidaySchedule_SeveralExpressions(t *testing.T) {
+	// The 15th and 16th of January are holidays.
+	var calendar = HolidayFunc(func(d time.Time) bool {
+		return d.Month() == time.January && (d.Day() == 15 || d.Day() == 16)
+	})
+
+	type Case struct {
+		name   string
+		sched  string
+		policy HolidayPolicy
+		from   time.Time
+		next   time.Time
+		prev   bool
+	}
+
+	for _, c := range []Case{
+		{name: "moved before a business day", sched: "*-*-* 09,15:00 UTC\n*-*-01,15 08:00 UTC", policy: PreviousBusinessDay, from: time.Date(2026, 01, 14, 1, 0, 0, 0, time.UTC), next: time.Date(2026, 01, 14, 8, 0, 0, 0, time.UTC)},
+		{name: "moved after a business day", sched: "*-*-* 09,15:00 UTC\n*-*-01,15 20:00 UTC", policy: NextBusinessDay, from: time.Date(2026, 01, 17, 23, 0, 0, 0, time.UTC), next: time.Date(2026, 01, 17, 20, 0, 0, 0, time.UTC), prev: true},
+		{name: "moved from another timezone", sched: "*-*-* 09:00 UTC\n*-*-15 23:30 America/New_York", policy: NextBusinessDay, from: time.Date(2026, 01, 18, 4, 0, 0, 0, time.UTC), next: time.Date(2026, 01, 18, 4, 30, 0, 0, time.UTC)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := MustParseSchedule(c.sched).WithHolidays(calendar, c.policy)
+
+			var out, ok = s.Next(c.from)
+			if c.prev {
+				out, ok = s.Prev(c.from)
+			}
+			if !ok || !out.Equal(c.next) {
+				t.Errorf("unexpected output: wanted %v, got %v %v", c.next, out, ok)
+			}
+		})
+	}
+}
+
+func TestHolidaySchedule_Occurrences(t *testing.T) {
+	var calendar = weekendCalendar
+
+	var (
+		from = time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+		to   = time.Date(2026, 12, 02, 0, 0, 0, 0, time.UTC)
+	)
+
+	type Case struct {
+		name     string
+		sched    string
+		policy   HolidayPolicy
+		expected []time.Time
+	}
+
+	for _, c := range []Case{
+		{
+			name:   "moved to an existing time",
+			sched:  "*-*-* 09:00 UTC",
+			policy: NextBusinessDay,
+			expected: []time.Time{
+				time.Date(2026, 11, 27, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 12, 01, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "moved after another time",
+			sched:  "Sat *-*-* 15:00 UTC\nMon *-*-* 09:00 UTC",
+			policy: NextBusinessDay,
+			expected: []time.Time{
+				time.Date(2026, 11, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 30, 15, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "moved before another time",
+			sched:  "Fri *-*-* 15:00 UTC\nSat *-*-* 09:00 UTC",
+			policy: PreviousBusinessDay,
+			expected: []time.Time{
+				time.Date(2026, 11, 27, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 27, 15, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "skipped",
+			sched:  "*-*-* 09:00 UTC",
+			policy: SkipHolidays,
+			expected: []time.Time{
+				time.Date(2026, 11, 27, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 12, 01, 9, 0, 0, 0, time.UTC),
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := MustParseSchedule(c.sched).WithHolidays(calendar, c.policy)
+
+			out := slices.Collect(s.Occurrences(from, to))
+			if !reflect.DeepEqual(c.expected, out) {
+				t.Errorf("unexpected output: wanted %v, got %v", c.expected, out)
+			}
+
+			// Prev must return the same points in time backward.
+			var backward []time.Time
+			for p, ok := s.Prev(to); ok && !p.Before(from); p, ok = s.Prev(p) {
+				backward = append([]time.Time{p}, backward...)
+			}
+			if !reflect.DeepEqual(c.expected, backward) {
+				t.Errorf("unexpected backward output: wanted %v, got %v", c.expected, backward)
+			}
+		})
+	}
+}
+
+func TestHolidaySchedule_Holidays(t *testing.T) {
+	var (
+		// holidays2026 has all the days of 2026 as holidays.
+		holidays2026 = HolidayFunc(func(d time.Time) bool { return d.Year() == 2026 })
+		always       = HolidayFunc(func(time.Time) bool { return true })
+		june         = time.Date(2026, 06, 01, 0, 0, 0, 0, time.UTC)
+		january      = time.Date(2027, 01, 01, 10, 0, 0, 0, time.UTC)
+	)
+
+	type Case struct {
+		name     string
+		calendar HolidayCalendar
+		policy   HolidayPolicy
+		from     time.Time
+		next     time.Time
+		prev     time.Time
+	}
+
+	for _, c := range []Case{
+		{name: "skip", calendar: holidays2026, policy: SkipHolidays, from: june, next: time.Date(2027, 01, 01, 0, 0, 0, 0, time.UTC), prev: time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{name: "next business day", calendar: holidays2026, policy: NextBusinessDay, from: june, next: time.Date(2027, 01, 01, 0, 0, 0, 0, time.UTC), prev: time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{name: "next business day after the holidays", calendar: holidays2026, policy: NextBusinessDay, from: january, next: time.Date(2027, 01, 01, 10, 0, 1, 0, time.UTC), prev: time.Date(2027, 01, 01, 9, 59, 59, 0, time.UTC)},
+		{name: "previous business day", calendar: holidays2026, policy: PreviousBusinessDay, from: june, next: time.Date(2027, 01, 01, 0, 0, 0, 0, time.UTC), prev: time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{name: "skip always", calendar: always, policy: SkipHolidays, from: june},
+		{name: "next business day always", calendar: always, policy: NextBusinessDay, from: june},
+		{name: "previous business day always", calendar: always, policy: PreviousBusinessDay, from: june},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := MustParse("*-*-* *:*:* UTC").WithHolidays(c.calendar, c.policy)
+
+			// The points in time of the holidays mustn't be checked
+			// one by one.
+			var start = time.Now()
+			next, nextOK := s.Next(c.from)
+			prev, prevOK := s.Prev(c.from)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("unexpected duration: got %v", elapsed)
+			}
+
+			if nextOK != !c.next.IsZero() || !next.Equal(c.next) {
+				t.Errorf("unexpected next: wanted %v, got %v %v", c.next, next, nextOK)
+			}
+			if prevOK != !c.prev.IsZero() || !prev.Equal(c.prev) {
+				t.Errorf("unexpected prev: wanted %v, got %v %v", c.prev, prev, prevOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package zcalendar
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// A Locale provides the words and phrases used to describe expressions in a
+// language.
+type Locale interface {
+	// Weekday returns the name of the weekday, from 1 for Monday to 7 for
+	// Sunday.
+	Weekday(day int) string
+
+	// Month returns the name of the month, from 1 for January to 12 for
+	// December.
+	Month(month int) string
+
+	// Ordinal returns the ordinal of the number, as used for the days of
+	// the month.
+	Ordinal(n int) string
+
+	// Phrase returns the format of a phrase, as used by fmt.Sprintf. The
+	// keys of the phrases and their arguments are listed in the English
+	// pack.
+	Phrase(key string) string
+}
+
+// A localePack is a Locale defined by tables.
+type localePack struct {
+	weekdays [7]string
+	months   [12]string
+	ordinal  func(n int) string
+	phrases  map[string]string
+}
+
+// Weekday implements the Locale interface.
+func (l *localePack) Weekday(day int) string {
+	return l.weekdays[day-1]
+}
+
+// Month implements the Locale interface.
+func (l *localePack) Month(month int) string {
+	return l.months[month-1]
+}
+
+// Ordinal implements the Locale interface.
+func (l *localePack) Ordinal(n int) string {
+	return l.ordinal(n)
+}
+
+// Phrase implements the Locale interface.
+func (l *localePack) Phrase(key string) string {
+	return l.phrases[key]
+}
+
+// English is the locale for the English language.
+var English Locale = &localePack{
+	weekdays: [7]string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
+	months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	ordinal: func(n int) string {
+		var suffix = "th"
+		switch {
+		case n%100 >= 11 && n%100 <= 13:
+		case n%10 == 1:
+			suffix = "st"
+		case n%10 == 2:
+			suffix = "nd"
+		case n%10 == 3:
+			suffix = "rd"
+		}
+		return strconv.Itoa(n) + suffix
+	},
+	phrases: map[string]string{
+		// Lists and ranges of items.
+		"and":   "and",
+		"range": "%s to %s",
+
+		// Times of day, with a list of times or values, or the step of
+		// the repetition.
+		"at":               "at %s",
+		"from":             "from %s to %s",
+		"starting at":      "starting at %s",
+		"every hour":       "every hour",
+		"every minute":     "every minute",
+		"every second":     "every second",
+		"every hours":      "every %s hours",
+		"every minutes":    "every %s minutes",
+		"every seconds":    "every %s seconds",
+		"at hour":          "at hour %s",
+		"at hours":         "at hours %s",
+		"at minute":        "at minute %s",
+		"at minutes":       "at minutes %s",
+		"at second":        "at second %s",
+		"at seconds":       "at seconds %s",
+		"during hour":      "during hour %s",
+		"during hours":     "during hours %s",
+		"during minute":    "during minute %s",
+		"during minutes":   "during minutes %s",
+		"repeated seconds": "%s every %s seconds",
+
+		// Days of the month, with an ordinal, or a number and its
+		// ordinal for the offsets from the end of the month, and the
+		// description of a day moved to the nearest weekday.
+		"day":             "the %s",
+		"day from end":    "the %[2]s to last day",
+		"last day":        "the last day",
+		"last days":       "the last %d days",
+		"nearest weekday": "the weekday nearest %s",
+
+		// Occurrences of a weekday in the month, with a number, its
+		// ordinal and the weekday.
+		"first weekday":       "the first %[3]s",
+		"nth weekday":         "the %[2]s %[3]s",
+		"last weekday":        "the last %[3]s",
+		"nth to last weekday": "the %[2]s to last %[3]s",
+
+		// Repetitions, with the number of units, its ordinal, and the
+		// description of the first value or range.
+		"every other day":    "every other day from %[3]s",
+		"every nth day":      "every %[2]s day from %[3]s",
+		"range every days":   "%[3]s every %[1]d days",
+		"every other month":  "every other month from %[3]s",
+		"every nth month":    "every %[2]s month from %[3]s",
+		"range every months": "%[3]s every %[1]d months",
+		"every other year":   "every other year from %[3]s",
+		"every nth year":     "every %[2]s year from %[3]s",
+		"range every years":  "%[3]s every %[1]d years",
+
+		// Repetitions of the ISO weeks and of the days of the year,
+		// which are numbers rather than dates.
+		"every other week":     "%[3]s and every other week after",
+		"every nth week":       "%[3]s and every %[2]s week after",
+		"range every weeks":    "%[3]s every %[1]d weeks",
+		"every other yearday":  "%[3]s and every other day after",
+		"every nth yearday":    "%[3]s and every %[2]s day after",
+		"range every yeardays": "%[3]s every %[1]d days",
+
+		// Clauses of the description, with the days, months, years,
+		// weekdays, ISO weeks and days of the year, then the whole
+		// description and the timezone.
+		"on days":                "on %s of the month",
+		"on days of months":      "on %s of %s",
+		"on odd days":            "on odd days of the month",
+		"on odd days of months":  "on odd days of %s",
+		"on even days":           "on even days of the month",
+		"on even days of months": "on even days of %s",
+		"days and weekdays":      "%s, %s",
+		"on weekdays":            "on %s",
+		"nth weekdays":           "%s of the month",
+		"on nth weekdays":        "on %s",
+		"in months":              "in %s",
+		"in years":               "in %s",
+		"in week":                "in ISO week %s",
+		"in weeks":               "in ISO weeks %s",
+		"on day of the year":     "on day %s of the year",
+		"on days of the year":    "on days %s of the year",
+		"city time":              "%s, %s time",
+		"zone time":              "%s, %s",
+
+		// Exclusions of a schedule, with the descriptions of its
+		// expressions and of its exclusions.
+		"except": "%s, except %s",
+	},
+}
+
+// French is the locale for the French language.
+var French Locale = &localePack{
+	weekdays: [7]string{"lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi", "dimanche"},
+	months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	ordinal: func(n int) string {
+		if n == 1 {
+			return "1er"
+		}
+		return strconv.Itoa(n)
+	},
+	phrases: map[string]string{
+		"and":   "et",
+		"range": "%s à %s",
+
+		"at":               "à %s",
+		"from":             "de %s à %s",
+		"starting at":      "à partir de %s",
+		"every hour":       "toutes les heures",
+		"every minute":     "toutes les minutes",
+		"every second":     "toutes les secondes",
+		"every hours":      "toutes les %s heures",
+		"every minutes":    "toutes les %s minutes",
+		"every seconds":    "toutes les %s secondes",
+		"at hour":          "à l'heure %s",
+		"at hours":         "aux heures %s",
+		"at minute":        "à la minute %s",
+		"at minutes":       "aux minutes %s",
+		"at second":        "à la seconde %s",
+		"at seconds":       "aux secondes %s",
+		"during hour":      "pendant l'heure %s",
+		"during hours":     "pendant les heures %s",
+		"during minute":    "pendant la minute %s",
+		"during minutes":   "pendant les minutes %s",
+		"repeated seconds": "%s toutes les %s secondes",
+
+		"day":             "le %s",
+		"day from end":    "le %[1]de dernier jour",
+		"last day":        "le dernier jour",
+		"last days":       "les %d derniers jours",
+		"nearest weekday": "%s ou le jour ouvré le plus proche",
+
+		"first weekday":       "premier %[3]s",
+		"nth weekday":         "%[1]de %[3]s",
+		"last weekday":        "dernier %[3]s",
+		"nth to last weekday": "%[1]de dernier %[3]s",
+
+		"every other day":    "un jour sur deux dès %[3]s",
+		"every nth day":      "tous les %[1]d jours dès %[3]s",
+		"range every days":   "%[3]s tous les %[1]d jours",
+		"every other month":  "un mois sur deux dès %[3]s",
+		"every nth month":    "tous les %[1]d mois dès %[3]s",
+		"range every months": "%[3]s tous les %[1]d mois",
+		"every other year":   "une année sur deux dès %[3]s",
+		"every nth year":     "tous les %[1]d ans dès %[3]s",
+		"range every years":  "%[3]s tous les %[1]d ans",
+
+		"every other week":     "%[3]s puis une semaine sur deux",
+		"every nth week":       "%[3]s puis toutes les %[1]d semaines",
+		"range every weeks":    "%[3]s toutes les %[1]d semaines",
+		"every other yearday":  "%[3]s puis un jour sur deux",
+		"every nth yearday":    "%[3]s puis tous les %[1]d jours",
+		"range every yeardays": "%[3]s tous les %[1]d jours",
+
+		"on days":                "%s du mois",
+		"on days of months":      "%s de %s",
+		"on odd days":            "les jours impairs du mois",
+		"on odd days of months":  "les jours impairs de %s",
+		"on even days":           "les jours pairs du mois",
+		"on even days of months": "les jours pairs de %s",
+		"days and weekdays":      "%s, %s",
+		"on weekdays":            "le %s",
+		"nth weekdays":           "%s du mois",
+		"on nth weekdays":        "le %s",
+		"in months":              "en %s",
+		"in years":               "en %s",
+		"in week":                "la semaine ISO %s",
+		"in weeks":               "les semaines ISO %s",
+		"on day of the year":     "le jour %s de l'année",
+		"on days of the year":    "les jours %s de l'année",
+		"city time":              "%s, heure de %s",
+		"zone time":              "%s, %s",
+
+		"except": "%s, sauf %s",
+	},
+}
+
+// German is the locale for the German language.
+var German Locale = &localePack{
+	weekdays: [7]string{"Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag", "Sonntag"},
+	months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	ordinal: func(n int) string {
+		return strconv.Itoa(n) + "."
+	},
+	phrases: map[string]string{
+		"and":   "und",
+		"range": "%s bis %s",
+
+		"at":               "um %s",
+		"from":             "von %s bis %s",
+		"starting at":      "ab %s",
+		"every hour":       "jede Stunde",
+		"every minute":     "jede Minute",
+		"every second":     "jede Sekunde",
+		"every hours":      "alle %s Stunden",
+		"every minutes":    "alle %s Minuten",
+		"every seconds":    "alle %s Sekunden",
+		"at hour":          "in Stunde %s",
+		"at hours":         "in den Stunden %s",
+		"at minute":        "in Minute %s",
+		"at minutes":       "in den Minuten %s",
+		"at second":        "in Sekunde %s",
+		"at seconds":       "in den Sekunden %s",
+		"during hour":      "während Stunde %s",
+		"during hours":     "während der Stunden %s",
+		"during minute":    "während Minute %s",
+		"during minutes":   "während der Minuten %s",
+		"repeated seconds": "%s alle %s Sekunden",
+
+		"day":             "%s",
+		"day from end":    "%[1]d.-letzten Tag",
+		"last day":        "letzten Tag",
+		"last days":       "letzten %d Tagen",
+		"nearest weekday": "%s oder nächsten Werktag",
+
+		"first weekday":       "ersten %[3]s",
+		"nth weekday":         "%[2]s %[3]s",
+		"last weekday":        "letzten %[3]s",
+		"nth to last weekday": "%[2]s letzten %[3]s",
+
+		"every other day":    "jeden zweiten Tag ab %[3]s",
+		"every nth day":      "jeden %[1]d. Tag ab %[3]s",
+		"range every days":   "%[3]s alle %[1]d Tage",
+		"every other month":  "jeden zweiten Monat ab %[3]s",
+		"every nth month":    "jeden %[1]d. Monat ab %[3]s",
+		"range every months": "%[3]s alle %[1]d Monate",
+		"every other year":   "jedes zweite Jahr ab %[3]s",
+		"every nth year":     "jedes %[1]d. Jahr ab %[3]s",
+		"range every years":  "%[3]s alle %[1]d Jahre",
+
+		"every other week":     "%[3]s und dann jede zweite Woche",
+		"every nth week":       "%[3]s und dann jede %[1]d. Woche",
+		"range every weeks":    "%[3]s alle %[1]d Wochen",
+		"every other yearday":  "%[3]s und dann jeden zweiten Tag",
+		"every nth yearday":    "%[3]s und dann jeden %[1]d. Tag",
+		"range every yeardays": "%[3]s alle %[1]d Tage",
+
+		"on days":                "am %s des Monats",
+		"on days of months":      "am %s im %s",
+		"on odd days":            "an ungeraden Tagen des Monats",
+		"on odd days of months":  "an ungeraden Tagen im %s",
+		"on even days":           "an geraden Tagen des Monats",
+		"on even days of months": "an geraden Tagen im %s",
+		"days and weekdays":      "%s, %s",
+		"on weekdays":            "am %s",
+		"nth weekdays":           "%s des Monats",
+		"on nth weekdays":        "am %s",
+		"in months":              "im %s",
+		"in years":               "im Jahr %s",
+		"in week":                "in der ISO-Woche %s",
+		"in weeks":               "in den ISO-Wochen %s",
+		"on day of the year":     "am Tag %s des Jahres",
+		"on days of the year":    "an den Tagen %s des Jahres",
+		"city time":              "%s, Ortszeit %s",
+		"zone time":              "%s, %s",
+
+		"except": "%s, außer %s",
+	},
+}
+
+// Spanish is the locale for the Spanish language.
+var Spanish Locale = &localePack{
+	weekdays: [7]string{"lunes", "martes", "miércoles", "jueves", "viernes", "sábado", "domingo"},
+	months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	ordinal: func(n int) string {
+		return strconv.Itoa(n)
+	},
+	phrases: map[string]string{
+		"and":   "y",
+		"range": "%s a %s",
+
+		"at":               "a las %s",
+		"from":             "de %s a %s",
+		"starting at":      "a partir de las %s",
+		"every hour":       "cada hora",
+		"every minute":     "cada minuto",
+		"every second":     "cada segundo",
+		"every hours":      "cada %s horas",
+		"every minutes":    "cada %s minutos",
+		"every seconds":    "cada %s segundos",
+		"at hour":          "en la hora %s",
+		"at hours":         "en las horas %s",
+		"at minute":        "en el minuto %s",
+		"at minutes":       "en los minutos %s",
+		"at second":        "en el segundo %s",
+		"at seconds":       "en los segundos %s",
+		"during hour":      "durante la hora %s",
+		"during hours":     "durante las horas %s",
+		"during minute":    "durante el minuto %s",
+		"during minutes":   "durante los minutos %s",
+		"repeated seconds": "%s cada %s segundos",
+
+		"day":             "el %s",
+		"day from end":    "el %[1]d.º último día",
+		"last day":        "el último día",
+		"last days":       "los últimos %d días",
+		"nearest weekday": "%s o el día laborable más cercano",
+
+		"first weekday":       "primer %[3]s",
+		"nth weekday":         "%[1]dº %[3]s",
+		"last weekday":        "último %[3]s",
+		"nth to last weekday": "%[1]dº %[3]s desde el final",
+
+		"every other day":    "un día de cada dos desde %[3]s",
+		"every nth day":      "cada %[1]d días desde %[3]s",
+		"range every days":   "%[3]s cada %[1]d días",
+		"every other month":  "un mes de cada dos desde %[3]s",
+		"every nth month":    "cada %[1]d meses desde %[3]s",
+		"range every months": "%[3]s cada %[1]d meses",
+		"every other year":   "un año de cada dos desde %[3]s",
+		"every nth year":     "cada %[1]d años desde %[3]s",
+		"range every years":  "%[3]s cada %[1]d años",
+
+		"every other week":     "%[3]s y luego una semana de cada dos",
+		"every nth week":       "%[3]s y luego cada %[1]d semanas",
+		"range every weeks":    "%[3]s cada %[1]d semanas",
+		"every other yearday":  "%[3]s y luego un día de cada dos",
+		"every nth yearday":    "%[3]s y luego cada %[1]d días",
+		"range every yeardays": "%[3]s cada %[1]d días",
+
+		"on days":                "%s del mes",
+		"on days of months":      "%s de %s",
+		"on odd days":            "los días impares del mes",
+		"on odd days of months":  "los días impares de %s",
+		"on even days":           "los días pares del mes",
+		"on even days of months": "los días pares de %s",
+		"days and weekdays":      "%s, %s",
+		"on weekdays":            "los %s",
+		"nth weekdays":           "%s de cada mes",
+		"on nth weekdays":        "el %s",
+		"in months":              "en %s",
+		"in years":               "en %s",
+		"in week":                "en la semana ISO %s",
+		"in weeks":               "en las semanas ISO %s",
+		"on day of the year":     "el día %s del año",
+		"on days of the year":    "los días %s del año",
+		"city time":              "%s, hora de %s",
+		"zone time":              "%s, %s",
+
+		"except": "%s, excepto %s",
+	},
+}
+
+// RegisterWeekdays adds the weekday names of the locale to the ones accepted
+// by Parse, in full or abbreviated to their first three letters. Expressions
+// are still marshaled with the English names. It is safe for concurrent use,
+// but changes the names accepted in the whole process, so it is best called
+// once, for example in an init function.
+func RegisterWeekdays(l Locale) error {
+	var names = make(map[string]int)
+	for day := 1; day <= 7; day++ {
+		name := strings.ToLower(l.Weekday(day))
+		names[name] = day
+
+		if runes := []rune(name); len(runes) > 3 {
+			names[string(runes[:3])] = day
+		}
+	}
+
+	weekdaysLock.Lock()
+	defer weekdaysLock.Unlock()
+
+	// Check all the names first so nothing is registered in case of
+	// conflict.
+	var current = *weekdaysValues.Load()
+	for name, day := range names {
+		if v, ok := current[name]; ok && v != day {
+			return fmt.Errorf("weekday %q is already registered for another day", name)
+		}
+	}
+
+	// The names are added to a copy, as the current map may be read.
+	var updated = maps.Clone(current)
+	maps.Copy(updated, names)
+	weekdaysValues.Store(&updated)
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package zcalendar
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestExpression_Describe_Locales(t *testing.T) {
+	type Case struct {
+		locale Locale
+		in     string
+		out    string
+	}
+
+	for _, c := range []Case{
+		{locale: French, in: "Mon..Fri *-*-01/2 08..18:00/15:00 Europe/Paris", out: "toutes les 15 minutes de 08:00 à 18:45 les jours impairs du mois, lundi à vendredi, heure de Paris"},
+		{locale: French, in: "*-01,07-01 00:00", out: "à 00:00 le 1er de janvier et juillet"},
+		{locale: French, in: "Sat 10:00 UTC", out: "à 10:00 le samedi, UTC"},
+		{locale: French, in: "Tue#1,Tue#3 10:00", out: "à 10:00 le premier mardi et 3e mardi du mois"},
+		{locale: German, in: "Fri#-1 10:00", out: "um 10:00 am letzten Freitag des Monats"},
+		{locale: Spanish, in: "Mon#2 10:00", out: "a las 10:00 el 2º lunes de cada mes"},
+		{locale: French, in: "*-*-15W 10:00", out: "à 10:00 le 15 ou le jour ouvré le plus proche du mois"},
+		{locale: German, in: "W10..20 12:00", out: "um 12:00 in den ISO-Wochen 10 bis 20"},
+		{locale: French, in: "W10 D70 12:00", out: "à 12:00 la semaine ISO 10 le jour 70 de l'année"},
+		{locale: Spanish, in: "D1,91,182,274 12:00", out: "a las 12:00 los días 1, 91, 182 y 274 del año"},
+		{locale: German, in: "Mon..Fri *-*-01/2 08..18:00/15:00 Europe/Berlin", out: "alle 15 Minuten von 08:00 bis 18:45 an ungeraden Tagen des Monats, Montag bis Freitag, Ortszeit Berlin"},
+		{locale: German, in: "*-*~01 12:00", out: "um 12:00 am letzten Tag des Monats"},
+		{locale: German, in: "hourly", out: "jede Stunde"},
+		{locale: Spanish, in: "Mon..Fri *-*-01/2 08..18:00/15:00 Europe/Madrid", out: "cada 15 minutos de 08:00 a 18:45 los días impares del mes, lunes a viernes, hora de Madrid"},
+		{locale: Spanish, in: "*-03-15 09:30", out: "a las 09:30 el 15 de marzo"},
+	} {
+		t.Run(c.in, func(t *testing.T) {
+			out := MustParse(c.in).Describe(c.locale)
+			if out != c.out {
+				t.Errorf("unexpected description: wanted %q, got %q", c.out, out)
+			}
+		})
+	}
+}
+
+func TestRegisterWeekdays(t *testing.T) {
+	var original = weekdaysValues.Load()
+	t.Cleanup(func() { weekdaysValues.Store(original) })
+
+	if err := RegisterWeekdays(French); err != nil {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+
+	exp, err := Parse("lundi,Mer..ven 10:00")
+	if err != nil {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+
+	if exp.String() != "Mon,Wed..Fri *-*-* 10:00:00" {
+		t.Errorf("unexpected expression: got %s", exp)
+	}
+
+	var conflicting = &localePack{weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}}
+	if err := RegisterWeekdays(conflicting); err == nil {
+		t.Errorf("expected an error for conflicting names")
+	}
+}
+
+func TestRegisterWeekdays_Concurrent(t *testing.T) {
+	var original = weekdaysValues.Load()
+	t.Cleanup(func() { weekdaysValues.Store(original) })
+
+	// The names may be registered while expressions are parsed.
+	var wg sync.WaitGroup
+	for _, l := range []Locale{French, German, Spanish} {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = RegisterWeekdays(l)
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := Parse("Mon..Fri 10:00"); err != nil {
+				t.Errorf("unexpected error: got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := Parse("lundi,Montag,lunes 10:00"); err != nil {
+		t.Errorf("unexpected error: got %v", err)
+	}
+}
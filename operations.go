@@ -0,0 +1,523 @@
+package zcalendar
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// A SetError is returned when the result of an operation on expressions can't
+// be represented in the calendar syntax.
+type SetError struct {
+	Feature string
+}
+
+// Error implements the error interface.
+func (e *SetError) Error() string {
+	return fmt.Sprintf("result can't be represented as an expression: %s", e.Feature)
+}
+
+// The indexes of the fields in fieldValues, from the biggest unit to the
+// smallest.
+const (
+	yearsIndex = iota
+	monthsIndex
+	daysIndex
+	weekdaysIndex
+	weeksIndex
+	yeardaysIndex
+	hoursIndex
+	minutesIndex
+	secondsIndex
+)
+
+// fieldValues holds the values matched by each field of an expression. As the
+// days depend on the number of days in the month, they are stored as 32 times
+// the number of days minus 28, plus the day. The weekdays are stored as 32
+// times the weekday, plus the bit of each of their occurrences, see
+// occurrenceBit.
+type fieldValues [9][]int
+
+// fieldValues returns the values matched by each field of the expression.
+func (e Expression) fieldValues() (f fieldValues) {
+	f[yearsIndex] = e.years.Values(MaxYears)
+	f[monthsIndex] = e.months.Values(12)
+	for i := 0; i < 4; i++ {
+		for _, day := range e.days.Values(28 + i) {
+			f[daysIndex] = append(f[daysIndex], 32*i+day)
+		}
+	}
+	for weekday, mask := range e.weekdays.occurrences() {
+		for bit := 0; bit < 32; bit++ {
+			if mask&(1<<bit) != 0 {
+				f[weekdaysIndex] = append(f[weekdaysIndex], 32*weekday+bit)
+			}
+		}
+	}
+	f[weeksIndex] = optional(e.weeks, allWeeks).Values(53)
+	f[yeardaysIndex] = optional(e.yeardays, allYeardays).Values(366)
+	f[hoursIndex] = e.hours.Values(23)
+	f[minutesIndex] = e.minutes.Values(59)
+	f[secondsIndex] = e.seconds.scaledValues(maxSeconds, microsecondsPerSecond)
+	return f
+}
+
+// lengths returns, for each number of days in a month minus 28, whether one of
+// the months of the values has this number of days.
+func (f fieldValues) lengths() (lengths [4]bool) {
+	for _, month := range f[monthsIndex] {
+		if month != 2 {
+			lengths[daysIn(MinYears, month)-28] = true
+			continue
+		}
+
+		for _, year := range f[yearsIndex] {
+			lengths[daysIn(year, month)-28] = true
+		}
+	}
+	return lengths
+}
+
+// empty returns whether one of the fields has no value, in which case the
+// values match nothing.
+func (f fieldValues) empty() bool {
+	for i, values := range f {
+		if i != daysIndex && len(values) == 0 {
+			return true
+		}
+	}
+
+	var lengths = f.lengths()
+	for _, v := range f[daysIndex] {
+		if lengths[v/32] {
+			return false
+		}
+	}
+	return true
+}
+
+// expression creates an expression matching the values in the timezone. The
+// values must not be empty.
+func (f fieldValues) expression(timezone *time.Location) (exp Expression, err error) {
+	days, err := f.days()
+	if err != nil {
+		return exp, err
+	}
+
+	weekdays, err := f.weekdays()
+	if err != nil {
+		return exp, err
+	}
+
+	exp = Expression{
+		weekdays: weekdays,
+		years:    componentsOf(f[yearsIndex], 1),
+		months:   componentsOf(f[monthsIndex], 1),
+		days:     days,
+		hours:    componentsOf(f[hoursIndex], 1),
+		minutes:  componentsOf(f[minutesIndex], 1),
+		seconds:  componentsOf(f[secondsIndex], microsecondsPerSecond),
+		weeks:    componentsOf(f[weeksIndex], 1),
+		yeardays: componentsOf(f[yeardaysIndex], 1),
+		timezone: timezone,
+	}.Normalize()
+	exp.sets = exp.compile()
+
+	return exp, nil
+}
+
+// weekdays returns the components matching the weekdays of the values on
+// their occurrences.
+func (f fieldValues) weekdays() (weekdays weekdayComponents, err error) {
+	var masks [8]uint32
+	for _, v := range f[weekdaysIndex] {
+		masks[v/32] |= 1 << (v % 32)
+	}
+
+	weekdays, ok := weekdaysOf(masks)
+	if !ok {
+		return nil, &SetError{Feature: "occurrences of weekdays"}
+	}
+
+	return weekdays, nil
+}
+
+// days returns the components matching the days of the values in each of
+// their months, either counted from the start or from the end of the month.
+func (f fieldValues) days() (components, error) {
+	var (
+		lengths = f.lengths()
+		days    [4][]int
+		longest int
+	)
+
+	for _, v := range f[daysIndex] {
+		days[v/32] = append(days[v/32], v%32)
+	}
+	for i := range lengths {
+		if lengths[i] {
+			longest = i
+		}
+	}
+
+	// Both ways of counting are tried using the days of the longest month,
+	// which contains all the others.
+	var (
+		fromStart = days[longest]
+		fromEnd   []int
+	)
+	for _, day := range slices.Backward(days[longest]) {
+		fromEnd = append(fromEnd, 28+longest+1-day)
+	}
+
+	var startOK, endOK = true, true
+	for i := range lengths {
+		if !lengths[i] {
+			continue
+		}
+
+		var start, end []int
+		for _, day := range fromStart {
+			if day <= 28+i {
+				start = append(start, day)
+			}
+		}
+		for _, offset := range slices.Backward(fromEnd) {
+			if day := 28 + i + 1 - offset; day >= 1 {
+				end = append(end, day)
+			}
+		}
+
+		startOK = startOK && slices.Equal(start, days[i])
+		endOK = endOK && slices.Equal(end, days[i])
+	}
+
+	switch {
+	case startOK && len(fromStart) == 28+longest:
+		return allDays, nil
+	case startOK:
+		return componentsOf(fromStart, 1), nil
+	case endOK:
+		var cs = componentsOf(fromEnd, 1)
+		for i := range cs {
+			cs[i].FromEnd = true
+		}
+		return cs, nil
+	}
+
+	return nil, &SetError{Feature: "days counted from both the start and the end of the month"}
+}
+
+// hasNearest returns whether the expression has days moved to the nearest
+// weekday, whose values depend on the month and can't be combined.
+func (e Expression) hasNearest() bool {
+	return len(e.days.nearest()) != 0
+}
+
+// hasUnlistedSeconds returns whether the expression has more than
+// maxListedValues seconds, as a sub-second value repeated every few
+// microseconds, whose values are too many to be combined.
+func (e Expression) hasUnlistedSeconds() bool {
+	return e.seconds.count(maxSeconds, microsecondsPerSecond) > maxListedValues
+}
+
+// intersectValues returns the values of a that are in b. Both must be sorted.
+func intersectValues(a, b []int) []int {
+	return slices.DeleteFunc(slices.Clone(a), func(v int) bool {
+		_, found := slices.BinarySearch(b, v)
+		return !found
+	})
+}
+
+// subtractValues returns the values of a that aren't in b. Both must be sorted.
+func subtractValues(a, b []int) []int {
+	return slices.DeleteFunc(slices.Clone(a), func(v int) bool {
+		_, found := slices.BinarySearch(b, v)
+		return found
+	})
+}
+
+// unionValues returns the values that are in a or b. Both must be sorted.
+func unionValues(a, b []int) []int {
+	var values = slices.Concat(a, b)
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+// intersection returns the values matched by both expressions in each of their
+// fields.
+func (e Expression) intersection(other Expression) fieldValues {
+	var values, others = e.fieldValues(), other.fieldValues()
+	for i := range values {
+		values[i] = intersectValues(values[i], others[i])
+	}
+	return values
+}
+
+// Intersect returns the expression matching the values matched by both
+// expressions in each of their fields. A *SetError is returned if the
+// expressions have different timezones, if one of them has days moved to the
+// nearest weekday or too many sub-second values, if the intersection matches
+// nothing, if its days can't be counted from one end of the month only, or if
+// the occurrences of one of its
+// weekdays can't be listed, such as the second Tuesday of the month that is
+// also its fourth to last one.
+func (e Expression) Intersect(other Expression) (exp Expression, err error) {
+	if e.timezone.String() != other.timezone.String() {
+		return exp, &SetError{Feature: "different timezones"}
+	}
+
+	if e.hasNearest() || other.hasNearest() {
+		return exp, &SetError{Feature: "nearest weekday"}
+	}
+
+	if e.hasUnlistedSeconds() || other.hasUnlistedSeconds() {
+		return exp, &SetError{Feature: "sub-second repeat"}
+	}
+
+	var values = e.intersection(other)
+	if values.empty() {
+		return exp, &SetError{Feature: "empty intersection"}
+	}
+
+	exp, err = values.expression(e.timezone)
+	if err != nil {
+		return exp, err
+	}
+
+	// The fields may have common values that are never matched together,
+	// such as a weekday and a day of the month on which it never falls.
+	if !exp.IsSatisfiable() {
+		return Expression{}, &SetError{Feature: "empty intersection"}
+	}
+
+	return exp, nil
+}
+
+// Union returns a schedule matching the points in time matched by either
+// expression. The expressions are merged when possible, i.e. when one
+// contains the other or when they only differ by one field. Like for the other
+// operations on expressions, their exclusion mark is ignored. The expressions
+// with days moved to the nearest weekday or with too many sub-second values are
+// only merged when they are equal.
+func (e Expression) Union(other Expression) Schedule {
+	e.excluded, other.excluded = false, false
+	if e.Equal(other) {
+		return Schedule{e}
+	}
+
+	if e.timezone.String() != other.timezone.String() || e.hasNearest() || other.hasNearest() ||
+		e.hasUnlistedSeconds() || other.hasUnlistedSeconds() {
+		return Schedule{e, other}
+	}
+
+	var (
+		values, others = e.fieldValues(), other.fieldValues()
+		differing      []int
+		contained      = true // Whether e contains other.
+		containing     = true // Whether other contains e.
+	)
+
+	for i := range values {
+		if !slices.Equal(values[i], others[i]) {
+			differing = append(differing, i)
+		}
+		contained = contained && len(subtractValues(others[i], values[i])) == 0
+		containing = containing && len(subtractValues(values[i], others[i])) == 0
+	}
+
+	switch {
+	case contained:
+		return Schedule{e}
+	case containing:
+		return Schedule{other}
+	case len(differing) == 1:
+		values[differing[0]] = unionValues(values[differing[0]], others[differing[0]])
+		if exp, err := values.expression(e.timezone); err == nil {
+			return Schedule{exp}
+		}
+	}
+
+	return Schedule{e, other}
+}
+
+// Subtract returns a schedule matching the points in time matched by the
+// expression but not by the other. The result is made of an expression for
+// each field that excludes some values of the other expression, and is empty
+// if the other expression contains this one. A *SetError is returned if the
+// expressions have different timezones, if one of them has days moved to the
+// nearest weekday or too many sub-second values, or if the days of the result
+// can't be counted from one end of the month only.
+func (e Expression) Subtract(other Expression) (s Schedule, err error) {
+	if e.timezone.String() != other.timezone.String() {
+		return nil, &SetError{Feature: "different timezones"}
+	}
+
+	if e.hasNearest() || other.hasNearest() {
+		return nil, &SetError{Feature: "nearest weekday"}
+	}
+
+	if e.hasUnlistedSeconds() || other.hasUnlistedSeconds() {
+		return nil, &SetError{Feature: "sub-second repeat"}
+	}
+
+	e.excluded = false
+
+	var common = e.intersection(other)
+	if common.empty() {
+		return Schedule{e}, nil
+	}
+
+	// The points in time matched by e but not by other are those which
+	// don't match other in a field, while matching it in the previous ones.
+	var values, others = e.fieldValues(), other.fieldValues()
+	for i := range values {
+		var piece fieldValues
+		copy(piece[:i], common[:i])
+		copy(piece[i+1:], values[i+1:])
+		piece[i] = subtractValues(values[i], others[i])
+
+		if piece.empty() {
+			continue
+		}
+
+		exp, err := piece.expression(e.timezone)
+		if err != nil {
+			return nil, err
+		}
+
+		if exp.IsSatisfiable() {
+			s = append(s, exp)
+		}
+	}
+
+	return s, nil
+}
+
+// resolved returns a schedule matching the same points in time without
+// exclusions, made of the differences between its expressions and its
+// exclusions.
+func (s Schedule) resolved() (Schedule, error) {
+	var included, excluded Schedule
+	for _, exp := range s {
+		if exp.excluded {
+			exp.excluded = false
+			excluded = append(excluded, exp)
+		} else {
+			included = append(included, exp)
+		}
+	}
+
+	if len(excluded) == 0 {
+		return included, nil
+	}
+
+	return included.Subtract(excluded)
+}
+
+// Intersect returns the schedule matching the points in time matched by both
+// schedules, made of the intersections of their expressions. The exclusions
+// are subtracted from the expressions first. A *SetError is returned if two
+// expressions have different timezones, if one of them has too many sub-second
+// values, or if the days of an intersection can't be counted from one end of
+// the month only.
+func (s Schedule) Intersect(other Schedule) (res Schedule, err error) {
+	s, err = s.resolved()
+	if err != nil {
+		return nil, err
+	}
+	other, err = other.resolved()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exp := range s {
+		for _, o := range other {
+			if exp.timezone.String() != o.timezone.String() {
+				return nil, &SetError{Feature: "different timezones"}
+			}
+
+			if exp.hasUnlistedSeconds() || o.hasUnlistedSeconds() {
+				return nil, &SetError{Feature: "sub-second repeat"}
+			}
+
+			var values = exp.intersection(o)
+			if values.empty() {
+				continue
+			}
+
+			intersection, err := values.expression(exp.timezone)
+			if err != nil {
+				return nil, err
+			}
+
+			if intersection.IsSatisfiable() && !slices.ContainsFunc(res, intersection.Equal) {
+				res = append(res, intersection)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// Union returns the schedule matching the points in time matched by either
+// schedule, made of their expressions without the duplicates. The exclusions
+// are subtracted from the expressions first, so that the exclusions of a
+// schedule don't apply to the other, which may return a *SetError in the
+// same cases as Expression.Subtract.
+func (s Schedule) Union(other Schedule) (res Schedule, err error) {
+	s, err = s.resolved()
+	if err != nil {
+		return nil, err
+	}
+	other, err = other.resolved()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exp := range slices.Concat(s, other) {
+		if !slices.ContainsFunc(res, exp.Equal) {
+			res = append(res, exp)
+		}
+	}
+	return res, nil
+}
+
+// Subtract returns the schedule matching the points in time matched by the
+// schedule but not by the other, made of the differences between its
+// expressions and each expression of the other. The exclusions are subtracted
+// from the expressions first. A *SetError is returned in the same cases as
+// Expression.Subtract.
+func (s Schedule) Subtract(other Schedule) (res Schedule, err error) {
+	s, err = s.resolved()
+	if err != nil {
+		return nil, err
+	}
+	other, err = other.resolved()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exp := range s {
+		var pieces = Schedule{exp}
+		for _, o := range other {
+			var next Schedule
+			for _, piece := range pieces {
+				difference, err := piece.Subtract(o)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, difference...)
+			}
+			pieces = next
+		}
+
+		for _, piece := range pieces {
+			if !slices.ContainsFunc(res, piece.Equal) {
+				res = append(res, piece)
+			}
+		}
+	}
+
+	return res, nil
+}
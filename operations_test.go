@@ -0,0 +1,201 @@
+package zcalendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkOperation checks that the schedule matches the points in time for which
+// match returns true, over a few years.
+func checkOperation(t *testing.T, s Schedule, match func(d time.Time) bool) {
+	t.Helper()
+
+	var (
+		from = time.Date(2023, 12, 25, 0, 0, 0, 0, time.UTC)
+		to   = time.Date(2025, 03, 05, 0, 0, 0, 0, time.UTC)
+	)
+
+	for d := from; d.Before(to); d = d.Add(30 * time.Minute) {
+		if s.Matches(d) != match(d) {
+			t.Errorf("unexpected match for %v: wanted %v, got %v", d, match(d), s.Matches(d))
+			return
+		}
+	}
+}
+
+// formatSchedule returns the expressions of the schedule, one per line.
+func formatSchedule(s Schedule) string {
+	var lines []string
+	for _, exp := range s {
+		lines = append(lines, exp.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestExpression_Intersect(t *testing.T) {
+	type Case struct {
+		name string
+		a, b string
+		out  string
+		err  string
+	}
+
+	for _, c := range []Case{
+		{name: "hours", a: "Mon..Fri *-*-* 08..18:00 UTC", b: "*-*-* 12..20:00 UTC", out: "Mon..Fri *-*-* 12..18:00:00 UTC"},
+		{name: "several fields", a: "*-*-01..15 *:00/15 UTC", b: "Sat,Sun *-*-10..20 *:00/10 UTC", out: "Sat,Sun *-*-10..15 *:00,30:00 UTC"},
+		{name: "days from end", a: "*-02-* UTC", b: "*-*~01 UTC", out: "*-02~01 00:00:00 UTC"},
+		{name: "full range", a: "*-*-* 0..23:00 UTC", b: "*-*-* *:00 UTC", out: "*-*-* *:00:00 UTC"},
+		{name: "occurrences", a: "Tue 12:00 UTC", b: "Tue#2,Fri#-1 12:00 UTC", out: "Tue#2 *-*-* 12:00:00 UTC"},
+		{name: "occurrences of both ends", a: "Fri#4 UTC", b: "Fri#-2 UTC", err: "result can't be represented as an expression: occurrences of weekdays"},
+		{name: "iso weeks", a: "W01..10 12:00 UTC", b: "W05..20 12:00 UTC", out: "W05..10 *-*-* 12:00:00 UTC"},
+		{name: "nearest weekday", a: "*-*-15W UTC", b: "*-*-* UTC", err: "result can't be represented as an expression: nearest weekday"},
+		{name: "empty", a: "*-*-* 08:00 UTC", b: "*-*-* 09:00 UTC", err: "result can't be represented as an expression: empty intersection"},
+		{name: "days of other months", a: "*-02-* UTC", b: "*-*-31 UTC", err: "result can't be represented as an expression: empty intersection"},
+		{name: "fields never matched together", a: "Tue *-*-01 UTC", b: "Tue#2 UTC", err: "result can't be represented as an expression: empty intersection"},
+		{name: "occurrences of both ends matched together", a: "Tue#2 UTC", b: "Tue#-4 UTC", err: "result can't be represented as an expression: occurrences of weekdays"},
+		{name: "timezones", a: "*-*-* 08:00 UTC", b: "*-*-* 08:00 Europe/Paris", err: "result can't be represented as an expression: different timezones"},
+		{name: "sub-second repeat", a: "*-*-* *:*:00/0.000001 UTC", b: "*-*-* 08:00 UTC", err: "result can't be represented as an expression: sub-second repeat"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := MustParse(c.a), MustParse(c.b)
+
+			out, err := a.Intersect(b)
+			if c.err != "" {
+				var serr *SetError
+				if !errors.As(err, &serr) || err.Error() != c.err {
+					t.Errorf("unexpected error: wanted %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if out.String() != c.out {
+				t.Errorf("unexpected output: wanted %q, got %q", c.out, out.String())
+			}
+
+			checkOperation(t, Schedule{out}, func(d time.Time) bool {
+				return a.Matches(d) && b.Matches(d)
+			})
+		})
+	}
+}
+
+func TestExpression_Union(t *testing.T) {
+	type Case struct {
+		name string
+		a, b string
+		out  string
+	}
+
+	for _, c := range []Case{
+		{name: "merged", a: "*-*-* 08:00 UTC", b: "*-*-* 09:00 UTC", out: "*-*-* 08,09:00:00 UTC"},
+		{name: "contained", a: "*-*-* 08:00 UTC", b: "*-*-* *:00 UTC", out: "*-*-* *:00:00 UTC"},
+		{name: "equal", a: "Mon..Sun 08:00 UTC", b: "*-*-* 08:00 UTC", out: "*-*-* 08:00:00 UTC"},
+		{name: "distinct", a: "Mon 08:00 UTC", b: "Tue 09:00 UTC", out: "Mon *-*-* 08:00:00 UTC\nTue *-*-* 09:00:00 UTC"},
+		{name: "occurrences", a: "Tue#1 08:00 UTC", b: "Tue#3 08:00 UTC", out: "Tue#1,Tue#3 *-*-* 08:00:00 UTC"},
+		{name: "nearest weekday", a: "*-*-15W 08:00 UTC", b: "*-*-15 08:00 UTC", out: "*-*-15W 08:00:00 UTC\n*-*-15 08:00:00 UTC"},
+		{name: "equal nearest weekday", a: "*-*-15W 08:00 UTC", b: "*-*-15W 08:00 UTC", out: "*-*-15W 08:00:00 UTC"},
+		{name: "timezones", a: "08:00 UTC", b: "08:00 Europe/Paris", out: "*-*-* 08:00:00 UTC\n*-*-* 08:00:00 Europe/Paris"},
+		{name: "sub-second repeat", a: "*-*-* 08:*:00/0.000001 UTC", b: "*-*-* 09:00 UTC", out: "*-*-* 08:*:00/0.000001 UTC\n*-*-* 09:00:00 UTC"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := MustParse(c.a), MustParse(c.b)
+
+			out := a.Union(b)
+
+			if text := formatSchedule(out); text != c.out {
+				t.Errorf("unexpected output: wanted %q, got %q", c.out, text)
+			}
+
+			checkOperation(t, out, func(d time.Time) bool {
+				return a.Matches(d) || b.Matches(d)
+			})
+		})
+	}
+}
+
+func TestExpression_Subtract(t *testing.T) {
+	type Case struct {
+		name string
+		a, b string
+		out  string
+		err  string
+	}
+
+	for _, c := range []Case{
+		{name: "lunch break", a: "Mon..Fri *-*-* 09..17:00/30 UTC", b: "*-*-* 12:*:* UTC", out: "Mon..Fri *-*-* 09..11,13..17:00,30:00 UTC"},
+		{name: "several fields", a: "*-*-* 08,09:00 UTC", b: "Sat,Sun *-*-* 08:00 UTC", out: "Mon..Fri *-*-* 08,09:00:00 UTC\nSat,Sun *-*-* 09:00:00 UTC"},
+		{name: "days from end", a: "*-*-* UTC", b: "*-*~01 UTC", out: "*-*~02..31 00:00:00 UTC"},
+		{name: "disjoint", a: "*-*-* 08:00 UTC", b: "*-*-* 09:00 UTC", out: "*-*-* 08:00:00 UTC"},
+		{name: "nearest weekday", a: "*-*-* 08:00 UTC", b: "*-*-15W 08:00 UTC", err: "result can't be represented as an expression: nearest weekday"},
+		{name: "occurrences", a: "Fri 08:00 UTC", b: "Fri#-1 08:00 UTC", out: "Fri#1,Fri#2,Fri#3,Fri#-2 *-*-* 08:00:00 UTC"},
+		{name: "contained", a: "*-*-* 08:00 UTC", b: "*-*-* *:00 UTC", out: ""},
+		{name: "days from both ends", a: "*-*~01 UTC", b: "*-*-31 UTC", err: "result can't be represented as an expression: days counted from both the start and the end of the month"},
+		{name: "timezones", a: "*-*-* 08:00 UTC", b: "*-*-* 08:00 Europe/Paris", err: "result can't be represented as an expression: different timezones"},
+		{name: "sub-second repeat", a: "*-*-* 08:00 UTC", b: "*-*-* *:*:00/0.000001 UTC", err: "result can't be represented as an expression: sub-second repeat"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := MustParse(c.a), MustParse(c.b)
+
+			out, err := a.Subtract(b)
+			if c.err != "" {
+				var serr *SetError
+				if !errors.As(err, &serr) || err.Error() != c.err {
+					t.Errorf("unexpected error: wanted %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if text := formatSchedule(out); text != c.out {
+				t.Errorf("unexpected output: wanted %q, got %q", c.out, text)
+			}
+
+			checkOperation(t, out, func(d time.Time) bool {
+				return a.Matches(d) && !b.Matches(d)
+			})
+		})
+	}
+}
+
+func TestSchedule_Operations(t *testing.T) {
+	var (
+		a = MustParseSchedule("Mon..Fri *-*-* 09..17:00 UTC\nSat *-*-* 10:00 UTC\n!2024-12-24..26 *:*:* UTC")
+		b = MustParseSchedule("*-*-* 12:00 UTC\n*-*-01 *:00 UTC\n!*-*-* 12:00 UTC")
+	)
+
+	intersection, err := a.Intersect(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkOperation(t, intersection, func(d time.Time) bool {
+		return a.Matches(d) && b.Matches(d)
+	})
+
+	union, err := a.Union(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkOperation(t, union, func(d time.Time) bool {
+		return a.Matches(d) || b.Matches(d)
+	})
+
+	difference, err := a.Subtract(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkOperation(t, difference, func(d time.Time) bool {
+		return a.Matches(d) && !b.Matches(d)
+	})
+
+	var plain = MustParseSchedule("Mon..Fri *-*-* 09..17:00 UTC\nSat *-*-* 10:00 UTC")
+	if union, _ := plain.Union(plain); !union.Equal(plain) || len(union) != len(plain) {
+		t.Errorf("unexpected union with itself: %v", union)
+	}
+}
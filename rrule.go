@@ -0,0 +1,838 @@
+package zcalendar
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The frequencies of a recurrence rule, from the smallest to the largest.
+const (
+	secondly = iota
+	minutely
+	hourly
+	daily
+	weekly
+	monthly
+	yearly
+)
+
+// rruleFrequencies list the valid values for the frequency of a recurrence
+// rule.
+var rruleFrequencies = map[string]int{
+	"SECONDLY": secondly,
+	"MINUTELY": minutely,
+	"HOURLY":   hourly,
+	"DAILY":    daily,
+	"WEEKLY":   weekly,
+	"MONTHLY":  monthly,
+	"YEARLY":   yearly,
+}
+
+// rruleFrequenciesStrings list the frequencies of a recurrence rule.
+var rruleFrequenciesStrings = []string{"SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+// rruleWeekdays list the valid values for the weekdays of a recurrence rule.
+var rruleWeekdays = map[string]int{
+	"MO": 1,
+	"TU": 2,
+	"WE": 3,
+	"TH": 4,
+	"FR": 5,
+	"SA": 6,
+	"SU": 7,
+}
+
+// rruleWeekdaysStrings list the weekdays of a recurrence rule.
+var rruleWeekdaysStrings = map[int]string{
+	1: "MO",
+	2: "TU",
+	3: "WE",
+	4: "TH",
+	5: "FR",
+	6: "SA",
+	7: "SU",
+}
+
+// rruleParts list the parts of a recurrence rule that are handled.
+var rruleParts = map[string]bool{
+	"FREQ":       true,
+	"INTERVAL":   true,
+	"COUNT":      true,
+	"UNTIL":      true,
+	"BYMONTH":    true,
+	"BYMONTHDAY": true,
+	"BYDAY":      true,
+	"BYHOUR":     true,
+	"BYMINUTE":   true,
+	"BYSECOND":   true,
+	"WKST":       true,
+}
+
+// maxRRuleCount is the biggest COUNT accepted by ParseRRule, as the
+// occurrences are iterated to find the end of the recurrence.
+const maxRRuleCount = 100000
+
+// ParseRRule parses an RFC 5545 recurrence rule into a schedule. The start of
+// the recurrence gives the default values of the rule as well as the timezone
+// of the expressions, and is expected to match the rule.
+//
+// The FREQ, INTERVAL, BYMONTH, BYMONTHDAY, BYDAY, BYHOUR, BYMINUTE, BYSECOND,
+// UNTIL and COUNT parts are supported, with the following restrictions:
+// - The interval must divide the number of values of the frequency's unit,
+// so it is the same across the bigger units, and must be 1 for daily and
+// weekly frequencies
+// - Numbered weekdays are only handled for monthly frequencies, or yearly
+// frequencies with months, and can't be combined with days of month
+// - The count can't be above 100000
+//
+// The conversion is exact: the schedule contains as many expressions as
+// needed to exclude the points in time before the start and after the end of
+// the recurrence.
+func ParseRRule(rule string, dtstart time.Time) (s Schedule, err error) {
+	parts, err := parseRRuleParts(strings.TrimPrefix(rule, "RRULE:"))
+	if err != nil {
+		return nil, err
+	}
+
+	freq, ok := rruleFrequencies[strings.ToUpper(parts["FREQ"])]
+	if !ok {
+		return nil, fmt.Errorf("invalid frequency %q", parts["FREQ"])
+	}
+
+	var interval = 1
+	if raw, ok := parts["INTERVAL"]; ok {
+		interval, err = strconv.Atoi(raw)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", raw)
+		}
+	}
+
+	if (freq == daily || freq == weekly) && interval != 1 {
+		return nil, fmt.Errorf("interval %d can't be represented for frequency %s", interval, parts["FREQ"])
+	}
+
+	var (
+		loc      = dtstart.Location()
+		byMonth  = parts["BYMONTH"] != ""
+		byDays   = parts["BYMONTHDAY"] != ""
+		byDay    = parts["BYDAY"] != ""
+		patterns []Expression
+	)
+
+	var exp = Expression{timezone: loc}
+
+	exp.seconds, err = rruleTimeComponents(parts["BYSECOND"], freq, secondly, interval, dtstart.Second(), 60, microsecondsPerSecond, allSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("parsing BYSECOND: %w", err)
+	}
+
+	exp.minutes, err = rruleTimeComponents(parts["BYMINUTE"], freq, minutely, interval, dtstart.Minute(), 60, 1, allMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing BYMINUTE: %w", err)
+	}
+
+	exp.hours, err = rruleTimeComponents(parts["BYHOUR"], freq, hourly, interval, dtstart.Hour(), 24, 1, allHours)
+	if err != nil {
+		return nil, fmt.Errorf("parsing BYHOUR: %w", err)
+	}
+
+	exp.years = allYears
+	if freq == yearly && interval != 1 {
+		exp.years = components{{From: dtstart.Year(), Repeat: interval}}
+	}
+
+	exp.months, err = rruleMonthComponents(parts["BYMONTH"], freq, interval, int(dtstart.Month()))
+	if err != nil {
+		return nil, fmt.Errorf("parsing BYMONTH: %w", err)
+	}
+	if freq == yearly && !byMonth && !byDays && !byDay {
+		exp.months = components{{From: int(dtstart.Month())}}
+	}
+
+	// The days of the month can be counted from the start or from the end
+	// of the month, which requires different expressions.
+	var days = []components{allDays}
+	switch {
+	case byDays:
+		days, err = rruleDayComponents(parts["BYMONTHDAY"])
+		if err != nil {
+			return nil, fmt.Errorf("parsing BYMONTHDAY: %w", err)
+		}
+	case (freq == monthly || freq == yearly) && !byDay:
+		days = []components{{{From: dtstart.Day()}}}
+	}
+
+	// Each numbered weekday requires a different expression, as it
+	// restricts the days of the month.
+	var weekdays = allWeekdays
+	if freq == weekly && !byDay {
+		weekdays = weekdayComponents{{From: weekdayOf(dtstart.Year(), int(dtstart.Month()), dtstart.Day())}}
+	}
+
+	var numbered map[int]weekdayComponents
+	if byDay {
+		weekdays, numbered, err = rruleWeekdayComponents(parts["BYDAY"])
+		if err != nil {
+			return nil, fmt.Errorf("parsing BYDAY: %w", err)
+		}
+
+		if len(numbered) != 0 && freq != monthly && (freq != yearly || !byMonth) {
+			return nil, errors.New("parsing BYDAY: numbered weekdays are only handled for monthly frequencies, or yearly frequencies with months")
+		}
+
+		if len(numbered) != 0 && byDays {
+			return nil, errors.New("parsing BYDAY: numbered weekdays can't be combined with BYMONTHDAY")
+		}
+	}
+
+	if weekdays != nil {
+		for _, d := range days {
+			var p = exp
+			p.weekdays = weekdays
+			p.days = d
+			patterns = append(patterns, p)
+		}
+	}
+
+	for _, n := range slices.Sorted(maps.Keys(numbered)) {
+		var p = exp
+		p.weekdays = numbered[n]
+		p.days = components{{From: (n-1)*7 + 1, To: min(n*7, 31)}}
+		if n < 0 {
+			p.days = components{{From: (-n-1)*7 + 1, To: -n * 7, FromEnd: true}}
+		}
+		patterns = append(patterns, p)
+	}
+
+	for i := range patterns {
+		patterns[i].sets = patterns[i].compile()
+	}
+
+	// Compute the end of the recurrence, which is the last occurrence when
+	// it is limited by a count.
+	var until = time.Date(MaxYears, 12, 31, 23, 59, 59, 999999000, loc)
+
+	_, hasCount := parts["COUNT"]
+	_, hasUntil := parts["UNTIL"]
+	if hasCount && hasUntil {
+		return nil, errors.New("COUNT and UNTIL can't be both set")
+	}
+
+	if hasUntil {
+		until, err = parseRRuleUntil(parts["UNTIL"], loc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing UNTIL: %w", err)
+		}
+	}
+
+	if hasCount {
+		count, err := strconv.Atoi(parts["COUNT"])
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid count %q", parts["COUNT"])
+		}
+		if count > maxRRuleCount {
+			return nil, fmt.Errorf("count %d above the maximum of %d", count, maxRRuleCount)
+		}
+
+		for n := range Schedule(patterns).Occurrences(dtstart, until) {
+			count--
+			if count == 0 {
+				until = n
+				break
+			}
+		}
+	}
+
+	for _, p := range patterns {
+		s = append(s, p.within(dtstart, until)...)
+	}
+
+	return s, nil
+}
+
+// MustParseRRule is like ParseRRule but will panic in case of error.
+func MustParseRRule(rule string, dtstart time.Time) (s Schedule) {
+	s, err := ParseRRule(rule, dtstart)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// parseRRuleParts parses the semicolon-separated parts of a recurrence rule.
+func parseRRuleParts(rule string) (parts map[string]string, err error) {
+	parts = make(map[string]string)
+
+	for index, part := range strings.Split(rule, ";") {
+		key, value, found := strings.Cut(part, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid part %d", index)
+		}
+
+		key = strings.ToUpper(key)
+		if !rruleParts[key] {
+			return nil, fmt.Errorf("unsupported part %s", key)
+		}
+
+		if _, ok := parts[key]; ok {
+			return nil, fmt.Errorf("duplicate part %s", key)
+		}
+
+		parts[key] = value
+	}
+
+	if _, ok := parts["FREQ"]; !ok {
+		return nil, errors.New("missing frequency")
+	}
+
+	return parts, nil
+}
+
+// parseRRuleList parses a comma-separated list of numbers of a recurrence
+// rule, which must be between min and max and not zero.
+func parseRRuleList(raw string, min, max int) (values []int, err error) {
+	for index, chunk := range strings.Split(raw, ",") {
+		v, err := strconv.Atoi(chunk)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value %d: %w`, index, err)
+		}
+
+		if v < min || v > max || (v == 0 && min < 0) {
+			return nil, fmt.Errorf("value %d out of bounds %d..%d", v, min, max)
+		}
+
+		values = append(values, v)
+	}
+
+	slices.Sort(values)
+	return slices.Compact(values), nil
+}
+
+// rruleTimeComponents create the components of a time unit of a recurrence
+// rule, from its list of values, the frequency and interval of the rule, the
+// level of the unit and its value at the start of the recurrence. The unit has
+// size values, all of them being matched by all, and is scaled by scale.
+func rruleTimeComponents(raw string, freq, level, interval, start, size, scale int, all components) (cs components, err error) {
+	var values []int
+	if raw != "" {
+		values, err = parseRRuleList(raw, 0, size-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case freq == level:
+		if size%interval != 0 {
+			return nil, fmt.Errorf("interval %d can't be represented", interval)
+		}
+
+		if raw == "" && interval == 1 {
+			return all, nil
+		}
+
+		if raw == "" {
+			return components{{From: start % interval * scale, Repeat: interval * scale}}, nil
+		}
+
+		values = slices.DeleteFunc(values, func(v int) bool { return (v-start)%interval != 0 })
+	case raw != "":
+	case freq < level:
+		return all, nil
+	default:
+		values = []int{start}
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("no value matching the interval")
+	}
+
+	for i := range values {
+		values[i] *= scale
+	}
+
+	return componentsOf(values, scale), nil
+}
+
+// rruleMonthComponents create the month components of a recurrence rule.
+func rruleMonthComponents(raw string, freq, interval, start int) (cs components, err error) {
+	var values []int
+	if raw != "" {
+		values, err = parseRRuleList(raw, 1, 12)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case freq == monthly:
+		if 12%interval != 0 {
+			return nil, fmt.Errorf("interval %d can't be represented", interval)
+		}
+
+		if raw == "" && interval == 1 {
+			return allMonths, nil
+		}
+
+		if raw == "" {
+			return components{{From: (start-1)%interval + 1, Repeat: interval}}, nil
+		}
+
+		values = slices.DeleteFunc(values, func(v int) bool { return (v-start)%interval != 0 })
+	case raw == "":
+		return allMonths, nil
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("no value matching the interval")
+	}
+
+	return componentsOf(values, 1), nil
+}
+
+// rruleDayComponents create the day components of a recurrence rule, the days
+// counted from the start and the end of the month being in different
+// components.
+func rruleDayComponents(raw string) (days []components, err error) {
+	values, err := parseRRuleList(raw, -31, 31)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromStart, fromEnd []int
+	for _, v := range values {
+		if v > 0 {
+			fromStart = append(fromStart, v)
+		} else {
+			fromEnd = append(fromEnd, -v)
+		}
+	}
+
+	if len(fromStart) != 0 {
+		days = append(days, componentsOf(fromStart, 1))
+	}
+
+	if len(fromEnd) != 0 {
+		slices.Sort(fromEnd)
+
+		cs := componentsOf(fromEnd, 1)
+		for i := range cs {
+			cs[i].FromEnd = true
+		}
+		days = append(days, cs)
+	}
+
+	return days, nil
+}
+
+// rruleWeekdayComponents create the weekday components of a recurrence rule.
+// The numbered weekdays are returned by number.
+func rruleWeekdayComponents(raw string) (weekdays weekdayComponents, numbered map[int]weekdayComponents, err error) {
+	numbered = make(map[int]weekdayComponents)
+
+	var plain [8]bool
+	for index, chunk := range strings.Split(raw, ",") {
+		if len(chunk) < 2 {
+			return nil, nil, fmt.Errorf("invalid weekday %d", index)
+		}
+
+		day, ok := rruleWeekdays[strings.ToUpper(chunk[len(chunk)-2:])]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid weekday %d", index)
+		}
+
+		if len(chunk) == 2 {
+			plain[day] = true
+			continue
+		}
+
+		n, err := strconv.Atoi(chunk[:len(chunk)-2])
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return nil, nil, fmt.Errorf("invalid weekday number %d", index)
+		}
+
+		numbered[n] = append(numbered[n], weekdayComponent{From: day})
+	}
+
+	for day := 1; day <= 7; day++ {
+		if plain[day] {
+			weekdays = append(weekdays, weekdayComponent{From: day})
+		}
+	}
+
+	return weekdays, numbered, nil
+}
+
+// parseRRuleUntil parses the end of a recurrence rule, which can be a date, a
+// local date-time or a UTC date-time.
+func parseRRuleUntil(raw string, loc *time.Location) (until time.Time, err error) {
+	switch len(raw) {
+	case len("20060102"):
+		until, err = time.ParseInLocation("20060102", raw, loc)
+		return until.AddDate(0, 0, 1).Add(-time.Microsecond), err
+	case len("20060102T150405"):
+		return time.ParseInLocation("20060102T150405", raw, loc)
+	default:
+		return time.Parse("20060102T150405Z", raw)
+	}
+}
+
+// A tuple is the list of the values of a point in time for each unit of an
+// expression, from the years to the seconds, which are in microseconds.
+type tuple [6]int
+
+// The bounds of each unit of a tuple. The days are always considered to go up
+// to 31, as the invalid days are never matched.
+var (
+	tupleMin = tuple{0, 1, 1, 0, 0, 0}
+	tupleMax = tuple{0, 12, 31, 23, 59, 60*microsecondsPerSecond - 1}
+)
+
+// tupleOf returns the tuple of the point in time.
+func tupleOf(t time.Time) tuple {
+	return tuple{t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()*microsecondsPerSecond + t.Nanosecond()/1000}
+}
+
+// date returns the point in time of the tuple in the location.
+func (t tuple) date(loc *time.Location) time.Time {
+	return time.Date(t[0], time.Month(t[1]), t[2], t[3], t[4], t[5]/microsecondsPerSecond, t[5]%microsecondsPerSecond*1000, loc)
+}
+
+// within returns the expressions matching the same points in time as e that
+// are between from and until, both included.
+//
+// The interval is split into blocks, each having the same values for the
+// biggest units and a range of values for the following one. The blocks at
+// the boundaries of the interval are only split further if they would
+// otherwise include a point in time matching e out of the interval.
+func (e Expression) within(from, until time.Time) (exps []Expression) {
+	if from.After(until) {
+		return nil
+	}
+
+	lo, hi := tupleOf(from.In(e.timezone)), tupleOf(until.In(e.timezone))
+
+	var b = bounder{exp: e}
+	b.split(0, lo, hi)
+
+	return b.exps
+}
+
+// A bounder splits an interval into blocks and restricts its expression to
+// each of them.
+type bounder struct {
+	exp  Expression
+	exps []Expression
+}
+
+// split splits the interval between lo and hi, which have the same values up
+// to the unit k.
+func (b *bounder) split(k int, lo, hi tuple) {
+	if k == len(lo)-1 {
+		b.emit(k, lo, lo[k], hi[k])
+		return
+	}
+
+	if lo[k] == hi[k] {
+		b.split(k+1, lo, hi)
+		return
+	}
+
+	var (
+		start, end = lo[k], hi[k]
+		lower      = b.tightLower(k, lo)
+		upper      = b.tightUpper(k, hi)
+	)
+
+	if lower {
+		b.above(k+1, lo)
+		start++
+	}
+
+	if upper {
+		end--
+	}
+
+	if start <= end {
+		b.emit(k, lo, start, end)
+	}
+
+	if upper {
+		b.below(k+1, hi)
+	}
+}
+
+// above splits the interval starting at lo and ending with the value of lo for
+// the unit k-1.
+func (b *bounder) above(k int, lo tuple) {
+	if k == len(lo)-1 {
+		b.emit(k, lo, lo[k], tupleMax[k])
+		return
+	}
+
+	var start = lo[k]
+	if b.tightLower(k, lo) {
+		b.above(k+1, lo)
+		start++
+	}
+
+	if start <= tupleMax[k] {
+		b.emit(k, lo, start, tupleMax[k])
+	}
+}
+
+// below splits the interval ending at hi and starting with the value of hi for
+// the unit k-1.
+func (b *bounder) below(k int, hi tuple) {
+	if k == len(hi)-1 {
+		b.emit(k, hi, tupleMin[k], hi[k])
+		return
+	}
+
+	var (
+		end   = hi[k]
+		upper = b.tightUpper(k, hi)
+	)
+
+	if upper {
+		end--
+	}
+
+	if tupleMin[k] <= end {
+		b.emit(k, hi, tupleMin[k], end)
+	}
+
+	if upper {
+		b.below(k+1, hi)
+	}
+}
+
+// tightLower returns whether the expression matches a point in time before lo
+// with the same values up to the unit k.
+func (b *bounder) tightLower(k int, lo tuple) bool {
+	var start = lo
+	copy(start[k+1:], tupleMin[k+1:])
+	if start == lo {
+		return false
+	}
+
+	p, ok := b.exp.Prev(lo.date(b.exp.timezone))
+	return ok && !p.Before(start.date(b.exp.timezone))
+}
+
+// tightUpper returns whether the expression matches a point in time after hi
+// with the same values up to the unit k.
+func (b *bounder) tightUpper(k int, hi tuple) bool {
+	// The end is computed as the start of the next value for the unit k,
+	// so the number of days in the month doesn't matter.
+	var end = hi
+	copy(end[k+1:], tupleMin[k+1:])
+	end[k]++
+
+	n, ok := b.exp.Next(hi.date(b.exp.timezone))
+	return ok && n.Before(end.date(b.exp.timezone))
+}
+
+// emit adds the expression restricted to the values of t up to the unit k,
+// and to the values between lo and hi for the unit k.
+func (b *bounder) emit(k int, t tuple, lo, hi int) {
+	var (
+		exp = b.exp
+		ok  bool
+	)
+
+	for j := 0; j <= k; j++ {
+		var from, to = t[j], t[j]
+		if j == k {
+			from, to = lo, hi
+		}
+
+		switch j {
+		case 0:
+			exp.years, ok = exp.years.restrict(from, to, MaxYears, 1)
+		case 1:
+			exp.months, ok = exp.months.restrict(from, to, 12, 1)
+		case 2:
+			exp.days, ok = exp.days.restrict(from, to, daysIn(t[0], t[1]), 1)
+		case 3:
+			exp.hours, ok = exp.hours.restrict(from, to, 23, 1)
+		case 4:
+			exp.minutes, ok = exp.minutes.restrict(from, to, 59, 1)
+		case 5:
+			exp.seconds, ok = exp.seconds.restrict(from, to, tupleMax[5], microsecondsPerSecond)
+		}
+
+		if !ok {
+			return
+		}
+	}
+
+	exp.sets = exp.compile()
+	b.exps = append(b.exps, exp)
+}
+
+// An RRuleError is returned when an expression uses a feature that can't be
+// represented in a recurrence rule.
+type RRuleError struct {
+	Feature string
+}
+
+// Error implements the error interface.
+func (e *RRuleError) Error() string {
+	return fmt.Sprintf("expression can't be represented as a recurrence rule: %s", e.Feature)
+}
+
+// RRule returns the RFC 5545 recurrence rule equivalent to the expression. The
+// rule has no timezone, and is meant to be used with a start in the timezone of
+// the expression, before its first occurrence. The occurrences of weekdays
+// are written as numbered weekdays of a monthly rule. A *RRuleError is returned if
+// the expression is an exclusion, restricts the years, the ISO weeks or the
+// days of the year, moves days to the nearest weekday or has sub-second values.
+//
+// The rule never has UNTIL or COUNT parts, so the bounded recurrences parsed
+// by ParseRRule can't be converted back: their bounds are stored as restricted
+// years, which are rejected.
+func (e Expression) RRule() (rule string, err error) {
+	if e.excluded {
+		return "", &RRuleError{Feature: "exclusion"}
+	}
+
+	if len(e.years.Values(MaxYears)) != MaxYears-MinYears+1 {
+		return "", &RRuleError{Feature: "year restriction"}
+	}
+
+	if e.hasNearest() {
+		return "", &RRuleError{Feature: "nearest weekday"}
+	}
+
+	if isRestricted(e.weeks, allWeeks, 53) {
+		return "", &RRuleError{Feature: "ISO week"}
+	}
+
+	if isRestricted(e.yeardays, allYeardays, 366) {
+		return "", &RRuleError{Feature: "day of year"}
+	}
+
+	if e.seconds.hasFraction(maxSeconds, microsecondsPerSecond) {
+		return "", &RRuleError{Feature: "sub-second"}
+	}
+
+	var seconds []int
+	for _, v := range e.seconds.scaledValues(maxSeconds, microsecondsPerSecond) {
+		seconds = append(seconds, v/microsecondsPerSecond)
+	}
+
+	var (
+		hours   = e.hours.Values(23)
+		minutes = e.minutes.Values(59)
+		freq    = daily
+	)
+
+	// Use the biggest frequency for which the smaller units don't have to
+	// be listed.
+	switch {
+	case len(seconds) == 60:
+		freq = secondly
+	case len(minutes) == 60:
+		freq = minutely
+	case len(hours) == 24:
+		freq = hourly
+	}
+
+	var parts = []string{"FREQ=" + rruleFrequenciesStrings[freq]}
+
+	if months := e.months.Values(12); len(months) != 12 {
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+
+	// The days counted from the end of the month are written as negative
+	// values, which are the same regardless of the number of days in the
+	// month.
+	var days []int
+	for _, c := range e.days {
+		for _, v := range (components{c}).Values(31) {
+			if c.FromEnd {
+				v -= 32
+			}
+			days = append(days, v)
+		}
+	}
+	slices.Sort(days)
+	days = slices.Compact(days)
+
+	if len(days) != 31 || days[0] < 0 {
+		slices.SortFunc(days, func(a, b int) int {
+			if (a < 0) != (b < 0) {
+				return b - a
+			}
+			return a - b
+		})
+		parts = append(parts, "BYMONTHDAY="+joinInts(days))
+	}
+
+	// The occurrences of the weekdays are numbered weekdays, which are
+	// only allowed with a monthly frequency.
+	if !e.weekdays.isAll() {
+		var names []string
+		for _, c := range e.weekdays.normalized() {
+			for _, v := range c.days() {
+				if c.Nth != 0 {
+					names = append(names, strconv.Itoa(c.Nth)+rruleWeekdaysStrings[v])
+				} else {
+					names = append(names, rruleWeekdaysStrings[v])
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+
+	if e.weekdays.hasNth() {
+		freq = monthly
+		parts[0] = "FREQ=" + rruleFrequenciesStrings[freq]
+	}
+
+	// The units smaller than the frequency must always be listed, while the
+	// others only restrict the occurrences.
+	if freq >= daily || len(hours) != 24 {
+		parts = append(parts, "BYHOUR="+joinInts(hours))
+	}
+
+	if freq > minutely || len(minutes) != 60 {
+		parts = append(parts, "BYMINUTE="+joinInts(minutes))
+	}
+
+	if freq > secondly {
+		parts = append(parts, "BYSECOND="+joinInts(seconds))
+	}
+
+	return strings.Join(parts, ";"), nil
+}
+
+// RRules returns the recurrence rules equivalent to the schedule.
+func (s Schedule) RRules() (rules []string, err error) {
+	for index, exp := range s {
+		rule, err := exp.RRule()
+		if err != nil {
+			return nil, fmt.Errorf(`converting expression %d: %w`, index, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// joinInts formats the values as a comma-separated list.
+func joinInts(values []int) string {
+	var parts = make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,165 @@
+package zcalendar
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	type Case struct {
+		name string
+		in   string
+		out  []time.Time
+		err  bool
+	}
+
+	var (
+		start = time.Date(2024, 01, 15, 9, 30, 0, 0, time.UTC)
+		at    = func(month, day, hour, minute int) time.Time {
+			return time.Date(2024, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+		}
+	)
+
+	for _, c := range []Case{
+		{name: "daily", in: "FREQ=DAILY;COUNT=3", out: []time.Time{at(1, 15, 9, 30), at(1, 16, 9, 30), at(1, 17, 9, 30)}},
+		{name: "prefix", in: "RRULE:FREQ=DAILY;COUNT=2", out: []time.Time{at(1, 15, 9, 30), at(1, 16, 9, 30)}},
+		{name: "weekly", in: "FREQ=WEEKLY;COUNT=3", out: []time.Time{at(1, 15, 9, 30), at(1, 22, 9, 30), at(1, 29, 9, 30)}},
+		{name: "weekdays", in: "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20240124", out: []time.Time{at(1, 15, 9, 30), at(1, 17, 9, 30), at(1, 22, 9, 30), at(1, 24, 9, 30)}},
+		{name: "until date-time", in: "FREQ=DAILY;UNTIL=20240117T093000Z", out: []time.Time{at(1, 15, 9, 30), at(1, 16, 9, 30), at(1, 17, 9, 30)}},
+		{name: "hourly", in: "FREQ=HOURLY;INTERVAL=6;UNTIL=20240116T000000Z", out: []time.Time{at(1, 15, 9, 30), at(1, 15, 15, 30), at(1, 15, 21, 30)}},
+		{name: "minutely", in: "FREQ=MINUTELY;INTERVAL=15;BYHOUR=9,10;COUNT=4", out: []time.Time{at(1, 15, 9, 30), at(1, 15, 9, 45), at(1, 15, 10, 0), at(1, 15, 10, 15)}},
+		{name: "monthly", in: "FREQ=MONTHLY;COUNT=3", out: []time.Time{at(1, 15, 9, 30), at(2, 15, 9, 30), at(3, 15, 9, 30)}},
+		{name: "month days", in: "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1,-1;COUNT=4", out: []time.Time{at(1, 31, 9, 30), at(4, 1, 9, 30), at(4, 30, 9, 30), at(7, 1, 9, 30)}},
+		{name: "numbered weekdays", in: "FREQ=MONTHLY;BYDAY=-1FR,2TU;COUNT=4", out: []time.Time{at(1, 26, 9, 30), at(2, 13, 9, 30), at(2, 23, 9, 30), at(3, 12, 9, 30)}},
+		{name: "yearly", in: "FREQ=YEARLY;BYMONTH=3;BYDAY=1SU;BYHOUR=8;BYMINUTE=0;COUNT=1", out: []time.Time{at(3, 3, 8, 0)}},
+		{name: "missing frequency", in: "COUNT=3", err: true},
+		{name: "invalid frequency", in: "FREQ=SOMETIMES", err: true},
+		{name: "duplicate part", in: "FREQ=DAILY;FREQ=WEEKLY", err: true},
+		{name: "unsupported part", in: "FREQ=YEARLY;BYWEEKNO=20", err: true},
+		{name: "daily interval", in: "FREQ=DAILY;INTERVAL=2", err: true},
+		{name: "uneven interval", in: "FREQ=MINUTELY;INTERVAL=7", err: true},
+		{name: "out of bounds", in: "FREQ=DAILY;BYHOUR=24", err: true},
+		{name: "numbered weekday", in: "FREQ=WEEKLY;BYDAY=1MO", err: true},
+		{name: "numbered weekday and day", in: "FREQ=MONTHLY;BYDAY=1MO;BYMONTHDAY=1", err: true},
+		{name: "count and until", in: "FREQ=DAILY;COUNT=1;UNTIL=20240201", err: true},
+		{name: "count too large", in: "FREQ=SECONDLY;COUNT=1000000000", err: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := ParseRRule(c.in, start)
+			if c.err != (err != nil) {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if c.err {
+				return
+			}
+
+			out := slices.Collect(s.Occurrences(start, time.Date(2030, 01, 01, 0, 0, 0, 0, time.UTC)))
+			if len(out) > len(c.out)+1 {
+				out = out[:len(c.out)+1]
+			}
+
+			if !slices.EqualFunc(out, c.out, time.Time.Equal) {
+				t.Errorf("unexpected occurrences: wanted %v, got %v", c.out, out)
+			}
+		})
+	}
+}
+
+func TestParseRRule_Unbounded(t *testing.T) {
+	var start = time.Date(2024, 01, 15, 9, 30, 0, 0, time.UTC)
+
+	s := MustParseRRule("FREQ=YEARLY;INTERVAL=2", start)
+	if len(s) != 1 || s[0].String() != "2024/2-01-15 09:30:00 UTC" {
+		t.Errorf("unexpected schedule: got %v", s)
+	}
+
+	next, ok := s.Next(time.Date(2100, 01, 01, 0, 0, 0, 0, time.UTC))
+	if !ok || !next.Equal(time.Date(2100, 01, 15, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next: got %v, %v", next, ok)
+	}
+}
+
+func TestExpression_RRule(t *testing.T) {
+	type Case struct {
+		name    string
+		in      string
+		out     string
+		feature string
+	}
+
+	for _, c := range []Case{
+		{name: "daily", in: "daily UTC", out: "FREQ=DAILY;BYHOUR=0;BYMINUTE=0;BYSECOND=0"},
+		{name: "weekdays", in: "Mon,Wed *-*-* 09:30 UTC", out: "FREQ=DAILY;BYDAY=MO,WE;BYHOUR=9;BYMINUTE=30;BYSECOND=0"},
+		{name: "end of month", in: "*-*~01,03 12:00 UTC", out: "FREQ=DAILY;BYMONTHDAY=-3,-1;BYHOUR=12;BYMINUTE=0;BYSECOND=0"},
+		{name: "month days", in: "*-01,07-01 00:00 UTC", out: "FREQ=DAILY;BYMONTH=1,7;BYMONTHDAY=1;BYHOUR=0;BYMINUTE=0;BYSECOND=0"},
+		{name: "hourly", in: "*:0/15 UTC", out: "FREQ=HOURLY;BYMINUTE=0,15,30,45;BYSECOND=0"},
+		{name: "minutely", in: "*-*-* 08..09:*:30 UTC", out: "FREQ=MINUTELY;BYHOUR=8,9;BYSECOND=30"},
+		{name: "secondly", in: "*-*-* *:*:* UTC", out: "FREQ=SECONDLY"},
+		{name: "repeated weekdays", in: "Tue/2 09:30 UTC", out: "FREQ=DAILY;BYDAY=TU,TH,SA;BYHOUR=9;BYMINUTE=30;BYSECOND=0"},
+		{name: "weekday occurrences", in: "Mon,Fri#-1 *-*-* 09:30 UTC", out: "FREQ=MONTHLY;BYDAY=MO,-1FR;BYHOUR=9;BYMINUTE=30;BYSECOND=0"},
+		{name: "hourly weekday occurrence", in: "Tue#2 *:00 UTC", out: "FREQ=MONTHLY;BYDAY=2TU;BYHOUR=0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23;BYMINUTE=0;BYSECOND=0"},
+		{name: "years", in: "2024-*-* 00:00 UTC", feature: "year restriction"},
+		{name: "nearest weekday", in: "*-*-15W 00:00 UTC", feature: "nearest weekday"},
+		{name: "iso week", in: "W02 00:00 UTC", feature: "ISO week"},
+		{name: "day of year", in: "D1 00:00 UTC", feature: "day of year"},
+		{name: "sub-second", in: "*-*-* 00:00:00.5 UTC", feature: "sub-second"},
+		{name: "sub-second repeat", in: "*-*-* *:*:00/0.000001 UTC", feature: "sub-second"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := MustParse(c.in).RRule()
+
+			var rerr *RRuleError
+			if c.feature != "" {
+				if !errors.As(err, &rerr) || rerr.Feature != c.feature {
+					t.Fatalf("unexpected error: wanted %s, got %v", c.feature, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			if out != c.out {
+				t.Errorf("unexpected output: wanted %s, got %s", c.out, out)
+			}
+		})
+	}
+}
+
+func TestExpression_RRule_RoundTrip(t *testing.T) {
+	var (
+		start = time.Date(2024, 01, 01, 0, 0, 0, 0, time.UTC)
+		end   = time.Date(2025, 01, 01, 0, 0, 0, 0, time.UTC)
+	)
+
+	for _, in := range []string{
+		"Mon..Fri *-*-* 09:30 UTC",
+		"*-*~01 12:00 UTC",
+		"*-02,08-01..07 00/6:00 UTC",
+		"*-*-* 08..09:*:30 UTC",
+	} {
+		t.Run(in, func(t *testing.T) {
+			exp := MustParse(in)
+
+			rule, err := exp.RRule()
+			if err != nil {
+				t.Fatalf("unexpected error: got %v", err)
+			}
+
+			s, err := ParseRRule(rule, start)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %s: got %v", rule, err)
+			}
+
+			wanted := slices.Collect(exp.Occurrences(start, end))
+			got := slices.Collect(s.Occurrences(start, end))
+			if !slices.EqualFunc(got, wanted, time.Time.Equal) {
+				t.Errorf("unexpected occurrences for %s: wanted %d, got %d", rule, len(wanted), len(got))
+			}
+		})
+	}
+}
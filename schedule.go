@@ -3,21 +3,33 @@ package zcalendar
 import (
 	"bytes"
 	"database/sql/driver"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"iter"
+	"slices"
 	"sort"
 	"strings"
 	"time"
+	"unicode"
 )
 
-// A Schedule represent a list of calendar expressions.
+// A Schedule represent a list of calendar expressions. The expressions marked as
+// exclusions remove their points in time from those of the other expressions.
 type Schedule []Expression
 
-// ParseSchedule parse a list of Expression separated by newlines.
+// ParseSchedule parse a list of Expression separated by newlines. The
+// expressions prefixed by a "!" are exclusions, e.g. "Mon..Fri 09:00" followed
+// by "!2026-12-24..26 *:*:*" matches the weekdays at 09:00 except from the 24th
+// to the 26th of December 2026. The offsets of the returned *ParseError are
+// relative to the whole list.
 func ParseSchedule(raw string) (s Schedule, err error) {
-	for index, rawExp := range strings.Split(raw, "\n") {
-		exp, err := Parse(rawExp)
+	lines, offsets := split(raw, "\n")
+	for index, rawExp := range lines {
+		exp, err := parseLine(rawExp)
 		if err != nil {
-			return s, fmt.Errorf(`parsing expression %d: %w`, index, err)
+			return s, wrapParseError(err, offsets[index], "parsing expression %d", index)
 		}
 
 		s = append(s, exp)
@@ -26,6 +38,28 @@ func ParseSchedule(raw string) (s Schedule, err error) {
 	return s, nil
 }
 
+// parseLine parses an expression of a schedule, which is an exclusion if it
+// starts with a "!". The offsets of the returned *ParseError are relative to
+// the line.
+func parseLine(raw string) (exp Expression, err error) {
+	var trimmed = strings.TrimLeftFunc(raw, unicode.IsSpace)
+	if !strings.HasPrefix(trimmed, "!") {
+		return Parse(raw)
+	}
+
+	var offset = len(raw) - len(trimmed) + 1
+	exp, err = Parse(raw[offset:])
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			perr.Offset += offset
+		}
+		return exp, err
+	}
+
+	return exp.Exclusion(), nil
+}
+
 // MustParseSchedule is like ParseSchedule but will panic in case of error.
 func MustParseSchedule(raw string) (s Schedule) {
 	s, err := ParseSchedule(raw)
@@ -48,9 +82,7 @@ func (s *Schedule) UnmarshalText(text []byte) (err error) {
 		if len(bytes.TrimSpace(raw)) == 0 {
 			continue
 		}
-		var exp Expression
-
-		err = exp.UnmarshalText(raw)
+		exp, err := parseLine(string(raw))
 		if err != nil {
 			return err
 		}
@@ -62,7 +94,8 @@ func (s *Schedule) UnmarshalText(text []byte) (err error) {
 	return nil
 }
 
-// MarshalText implements the encoding.MarshalText interface.
+// MarshalText implements the encoding.MarshalText interface. The expressions
+// are written on separate lines, with a "!" prefix for the exclusions.
 func (s Schedule) MarshalText() (text []byte, err error) {
 	var expressions [][]byte
 	for index, exp := range s {
@@ -71,10 +104,14 @@ func (s Schedule) MarshalText() (text []byte, err error) {
 			return nil, fmt.Errorf(`marshaling expression %d: %w`, index, err)
 		}
 
+		if exp.excluded {
+			text = append([]byte("!"), text...)
+		}
+
 		expressions = append(expressions, text)
 	}
 
-	return bytes.Join(expressions, []byte("\"")), nil
+	return bytes.Join(expressions, []byte("\n")), nil
 }
 
 // Scan implements the sql.Scanner interface, which allow to use a Schedule as
@@ -100,15 +137,114 @@ func (s Schedule) Value() (val driver.Value, err error) {
 	return s.MarshalText()
 }
 
+// Matches returns whether the point in time satisfies any expression, and none
+// of the exclusions.
+func (s Schedule) Matches(d time.Time) bool {
+	var matches bool
+	for _, e := range s {
+		if e.Matches(d) {
+			if e.excluded {
+				return false
+			}
+			matches = true
+		}
+	}
+	return matches
+}
+
+// IsSatisfiable returns whether at least one of the points in time of the
+// expressions of the schedule isn't excluded.
+func (s Schedule) IsSatisfiable() bool {
+	var satisfiable, exclusions bool
+	for _, exp := range s {
+		switch {
+		case exp.excluded:
+			exclusions = true
+		case exp.IsSatisfiable():
+			satisfiable = true
+		}
+	}
+
+	if !satisfiable || !exclusions {
+		return satisfiable
+	}
+
+	// The exclusions may cover all the points in time of the expressions.
+	_, ok := s.Next(time.Date(MinYears-1, 12, 31, 0, 0, 0, 0, time.UTC))
+	return ok
+}
+
+// Equal returns whether both schedules have equal expressions, regardless of
+// their order and of duplicates.
+func (s Schedule) Equal(other Schedule) bool {
+	for _, exp := range s {
+		if !slices.ContainsFunc(other, exp.Equal) {
+			return false
+		}
+	}
+	for _, exp := range other {
+		if !slices.ContainsFunc(s, exp.Equal) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Hash returns a hash of the schedule, which is the same for equal schedules.
+// It is stable across processes, so it can be stored to detect duplicates.
+func (s Schedule) Hash() uint64 {
+	var hashes = make([]uint64, 0, len(s))
+	for _, exp := range s {
+		hashes = append(hashes, exp.Hash())
+	}
+	slices.Sort(hashes)
+	hashes = slices.Compact(hashes)
+
+	var (
+		h   = fnv.New64a()
+		buf = make([]byte, 0, 8*len(hashes))
+	)
+	for _, v := range hashes {
+		buf = binary.LittleEndian.AppendUint64(buf, v)
+	}
+	h.Write(buf)
+
+	return h.Sum64()
+}
+
 // Next return the first valid date represented by any expression that is after
-// d.
+// d and isn't excluded.
 func (s Schedule) Next(d time.Time) (n time.Time, ok bool) {
+	for {
+		n, ok = s.next(d)
+		if !ok {
+			return
+		}
+
+		// Continue from the end of the excluded points in time, which is
+		// at least the end of the minute when the exclusions together
+		// cover all its seconds matched by the expressions.
+		_, end, excluded := s.exclusion(n)
+		if !excluded {
+			return n, true
+		}
+		d = end.Add(-time.Microsecond)
+	}
+}
+
+// next is like Next, without the exclusions.
+func (s Schedule) next(d time.Time) (n time.Time, ok bool) {
 	if len(s) == 0 {
 		return
 	}
 
 	var candidates []time.Time
 	for _, e := range s {
+		if e.excluded {
+			continue
+		}
+
 		next, ok := e.Next(d)
 		if !ok {
 			continue
@@ -127,3 +263,242 @@ func (s Schedule) Next(d time.Time) (n time.Time, ok bool) {
 
 	return candidates[0], true
 }
+
+// Prev return the last valid date represented by any expression that is before
+// d and isn't excluded.
+func (s Schedule) Prev(d time.Time) (p time.Time, ok bool) {
+	for {
+		p, ok = s.prev(d)
+		if !ok {
+			return
+		}
+
+		start, _, excluded := s.exclusion(p)
+		if !excluded {
+			return p, true
+		}
+		d = start
+	}
+}
+
+// prev is like Prev, without the exclusions.
+func (s Schedule) prev(d time.Time) (p time.Time, ok bool) {
+	if len(s) == 0 {
+		return
+	}
+
+	var candidates []time.Time
+	for _, e := range s {
+		if e.excluded {
+			continue
+		}
+
+		prev, ok := e.Prev(d)
+		if !ok {
+			continue
+		}
+
+		candidates = append(candidates, prev)
+	}
+
+	if len(candidates) == 0 {
+		return
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].After(candidates[j])
+	})
+
+	return candidates[0], true
+}
+
+// exclusion returns whether the point in time is excluded, and if so the
+// widest window around it, from start included to end excluded, in which all
+// the points in time of the expressions are excluded.
+func (s Schedule) exclusion(d time.Time) (start, end time.Time, excluded bool) {
+	for _, e := range s {
+		if e.excluded && e.Matches(d) {
+			start, end = s.window(d)
+			return start, end, true
+		}
+	}
+
+	return d, d, false
+}
+
+// window returns the biggest window of time around d, among its minute, its
+// hour, and the days around it within its year, whose points in time matched
+// by the expressions are all matched by the exclusions together, or d itself
+// if there is none. An exclusion must match d.
+func (s Schedule) window(d time.Time) (start, end time.Time) {
+	var (
+		timezone       *time.Location
+		included       []sets
+		hours, minutes bitset
+	)
+	for _, e := range s {
+		if e.excluded {
+			continue
+		}
+
+		// The units of other timezones may not be aligned.
+		if timezone != nil && e.timezone.String() != timezone.String() {
+			return d, d.Add(time.Microsecond)
+		}
+		timezone = e.timezone
+		included = append(included, e.compiled())
+		hours |= included[len(included)-1].hours
+		minutes |= included[len(included)-1].minutes
+	}
+	if timezone == nil {
+		return d, d.Add(time.Microsecond)
+	}
+
+	var exclusions []sets
+	for _, e := range s {
+		// Ignoring exclusions only narrows the window, and the masks
+		// of exclusions below are limited to 64 of them.
+		if e.excluded && e.timezone.String() == timezone.String() && len(exclusions) < 64 {
+			exclusions = append(exclusions, e.compiled())
+		}
+	}
+
+	var (
+		// The minutes are grouped by the exclusions matching them, as
+		// the seconds of each group only need to be checked once.
+		checked = make(map[[2]uint64]bool)
+		covered = func(dates uint64, h, m int) bool {
+			var mask uint64
+			for i := range exclusions {
+				if dates&(1<<i) != 0 && exclusions[i].hours.Contains(h) && exclusions[i].minutes.Contains(m) {
+					mask |= 1 << i
+				}
+			}
+
+			for i := range included {
+				if !included[i].hours.Contains(h) || !included[i].minutes.Contains(m) {
+					continue
+				}
+
+				var key = [2]uint64{uint64(i), mask}
+				if _, ok := checked[key]; !ok {
+					checked[key] = excludesSeconds(exclusions, mask, &included[i])
+				}
+				if !checked[key] {
+					return false
+				}
+			}
+			return true
+		}
+		coveredHour = func(dates uint64, h int) bool {
+			for m := 0; m < 60; m++ {
+				if minutes.Contains(m) && !covered(dates, h, m) {
+					return false
+				}
+			}
+			return true
+		}
+
+		// The days are grouped by the exclusions matching their date.
+		days       = make(map[uint64]bool)
+		coveredDay = func(dates uint64) bool {
+			if _, ok := days[dates]; !ok {
+				days[dates] = true
+				for h := 0; h < 24; h++ {
+					if hours.Contains(h) && !coveredHour(dates, h) {
+						days[dates] = false
+						break
+					}
+				}
+			}
+			return days[dates]
+		}
+
+		// skipped returns whether all the points in time of the date
+		// matched by the expressions are excluded.
+		skipped = func(date time.Time) bool {
+			var (
+				year, month, day = date.Date()
+				dates            uint64
+				matched          bool
+			)
+			for i := range included {
+				matched = matched || included[i].containsDate(year, int(month), day)
+			}
+			if !matched {
+				return true
+			}
+
+			for i := range exclusions {
+				if exclusions[i].containsDate(year, int(month), day) {
+					dates |= 1 << i
+				}
+			}
+			return coveredDay(dates)
+		}
+	)
+
+	d = d.In(timezone)
+	var (
+		year, month, day = d.Date()
+		minute           = d.Truncate(time.Minute)
+		hour             = minute.Add(-time.Duration(d.Minute()) * time.Minute)
+		dates            uint64
+	)
+	for i := range exclusions {
+		if exclusions[i].containsDate(year, int(month), day) {
+			dates |= 1 << i
+		}
+	}
+
+	switch {
+	case !covered(dates, d.Hour(), d.Minute()):
+		return d, d.Add(time.Microsecond)
+	case !coveredHour(dates, d.Hour()):
+		return minute, minute.Add(time.Minute)
+	case !coveredDay(dates):
+		return hour, hour.Add(time.Hour)
+	}
+
+	// The window is widened to the days around d that are skipped as
+	// well, up to its whole year, so that the dates entirely excluded are
+	// skipped by years rather than days.
+	var (
+		first, last = d.YearDay(), d.YearDay()
+		yeardays    = time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	)
+	for first > 1 && skipped(time.Date(year, 1, first-1, 0, 0, 0, 0, time.UTC)) {
+		first--
+	}
+	for last < yeardays && skipped(time.Date(year, 1, last+1, 0, 0, 0, 0, time.UTC)) {
+		last++
+	}
+	return time.Date(year, 1, first, 0, 0, 0, 0, timezone), time.Date(year, 1, last+1, 0, 0, 0, 0, timezone)
+}
+
+// excludesSeconds returns whether all the seconds of the other sets are in
+// those of the exclusions of the mask together. The sub-second values are only
+// compared to each exclusion separately.
+func excludesSeconds(exclusions []sets, mask uint64, other *sets) bool {
+	var union bitset
+	for i := range exclusions {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		if exclusions[i].containsSeconds(other) {
+			return true
+		}
+		if exclusions[i].subseconds == nil {
+			union |= exclusions[i].seconds
+		}
+	}
+
+	return other.subseconds == nil && other.seconds&^union == 0
+}
+
+// Occurrences returns an iterator over the points in time that satisfy any
+// expression between from, included, and to, excluded. The points in time are
+// ordered, and those satisfying several expressions are only returned once.
+func (s Schedule) Occurrences(from, to time.Time) iter.Seq[time.Time] {
+	return occurrences(s.Next, from, to)
+}
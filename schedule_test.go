@@ -0,0 +1,233 @@
+package zcalendar
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Matches(t *testing.T) {
+	var sched = MustParseSchedule("*-*-* 12:00 UTC\nSat,Sun *-*-* 10:00 UTC")
+
+	for d, match := range map[time.Time]bool{
+		time.Date(2006, 01, 02, 12, 0, 0, 0, time.UTC): true,
+		time.Date(2006, 01, 01, 10, 0, 0, 0, time.UTC): true,
+		time.Date(2006, 01, 02, 10, 0, 0, 0, time.UTC): false,
+	} {
+		if out := sched.Matches(d); out != match {
+			t.Errorf("unexpected output for %v: wanted %v, got %v", d, match, out)
+		}
+	}
+}
+
+func TestSchedule_IsSatisfiable(t *testing.T) {
+	if !MustParseSchedule("Mon 2024-01-02\nMon 2024-01-01").IsSatisfiable() {
+		t.Errorf("expected the schedule to be satisfiable")
+	}
+
+	if MustParseSchedule("Mon 2024-01-02\nTue 2024-01-01").IsSatisfiable() {
+		t.Errorf("expected the schedule not to be satisfiable")
+	}
+
+	if (Schedule{}).IsSatisfiable() {
+		t.Errorf("expected the empty schedule not to be satisfiable")
+	}
+}
+
+func TestSchedule_Exclusions(t *testing.T) {
+	var raw = "Mon..Fri *-*-* 09:00:00 UTC\n!2026-12-24..26 *:*:* UTC"
+
+	sched, err := ParseSchedule(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !sched[1].IsExclusion() || sched[0].IsExclusion() {
+		t.Errorf("unexpected exclusions: %v", sched)
+	}
+
+	text, err := sched.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(text) != raw {
+		t.Errorf("unexpected marshaling: wanted %q, got %q", raw, text)
+	}
+
+	var unmarshaled Schedule
+	if err := unmarshaled.UnmarshalText(text); err != nil || !unmarshaled.Equal(sched) {
+		t.Errorf("unexpected unmarshaling: %v, %v", unmarshaled, err)
+	}
+
+	for d, match := range map[time.Time]bool{
+		time.Date(2026, 12, 23, 9, 0, 0, 0, time.UTC): true,
+		time.Date(2026, 12, 24, 9, 0, 0, 0, time.UTC): false,
+		time.Date(2026, 12, 28, 9, 0, 0, 0, time.UTC): true,
+	} {
+		if out := sched.Matches(d); out != match {
+			t.Errorf("unexpected match for %v: wanted %v, got %v", d, match, out)
+		}
+	}
+
+	var (
+		from     = time.Date(2026, 12, 23, 0, 0, 0, 0, time.UTC)
+		to       = time.Date(2026, 12, 30, 0, 0, 0, 0, time.UTC)
+		expected = []time.Time{
+			time.Date(2026, 12, 23, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 12, 28, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 12, 29, 9, 0, 0, 0, time.UTC),
+		}
+	)
+	if out := slices.Collect(sched.Occurrences(from, to)); !reflect.DeepEqual(expected, out) {
+		t.Errorf("unexpected occurrences: wanted %v, got %v", expected, out)
+	}
+
+	if sched.Equal(Schedule{sched[0], sched[1].Exclusion(), MustParse("2026-12-24..26 *:*:* UTC")}) {
+		t.Errorf("expected an exclusion not to be equal to an expression")
+	}
+
+	if MustParseSchedule("*-*-* 12:00 UTC\n!*-*-* 12..13:00 UTC").IsSatisfiable() {
+		t.Errorf("expected a fully excluded schedule not to be satisfiable")
+	}
+}
+
+func TestSchedule_Equal(t *testing.T) {
+	var (
+		sched = MustParseSchedule("*-*-* 12:00 UTC\nSat,Sun *-*-* 10:00 UTC")
+		same  = MustParseSchedule("Sun,Sat *-*-* 10:00 UTC\n*-*-* 12:00:00 UTC\n12:00 UTC")
+		other = MustParseSchedule("*-*-* 12:00 UTC\nSat *-*-* 10:00 UTC")
+	)
+
+	if !sched.Equal(same) || sched.Hash() != same.Hash() {
+		t.Errorf("expected the schedules to be equal")
+	}
+
+	if sched.Equal(other) || other.Equal(sched) {
+		t.Errorf("expected the schedules not to be equal")
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	type Case struct {
+		name  string
+		sched string
+		next  time.Time
+		found bool
+	}
+
+	for _, c := range []Case{
+		{name: "earliest expression", sched: "*-*-* 18:00 UTC\n*-*-* 16:00 UTC", next: time.Date(2006, 01, 02, 16, 0, 0, 0, time.UTC), found: true},
+		{name: "skip expired expression", sched: "2005-*-* UTC\n*-*-* 18:00 UTC", next: time.Date(2006, 01, 02, 18, 0, 0, 0, time.UTC), found: true},
+		{name: "no next date", sched: "2005-*-* UTC", found: false},
+		{name: "excluded day", sched: "*-*-* 16:00 UTC\n!2006-01-02 *:*:* UTC", next: time.Date(2006, 01, 03, 16, 0, 0, 0, time.UTC), found: true},
+		{name: "excluded time", sched: "*-*-* 16,18:00 UTC\n!*-*-* 16:00 UTC", next: time.Date(2006, 01, 02, 18, 0, 0, 0, time.UTC), found: true},
+		{name: "excluded minutes", sched: "*-*-* *:*:00/30 UTC\n!*-*-* 15:04..59:* UTC", next: time.Date(2006, 01, 02, 16, 0, 0, 0, time.UTC), found: true},
+		{name: "all excluded", sched: "2006-*-* 16:00 UTC\n!2006-*-* *:*:* UTC", found: false},
+		{name: "split exclusions", sched: "*-*-* 15,16:00 UTC\n!2006-01-02 15:*:* UTC\n!2006-01-02 16:*:* UTC", next: time.Date(2006, 01, 03, 15, 0, 0, 0, time.UTC), found: true},
+		{name: "partially split exclusions", sched: "*-*-* *:*:00/20 UTC\n!2006-01-02 15:*:00 UTC\n!2006-01-02 15:*:20 UTC", next: time.Date(2006, 01, 02, 15, 4, 40, 0, time.UTC), found: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, ok := MustParseSchedule(c.sched).Next(current)
+			if ok != c.found {
+				t.Fatalf("unexpected found output: wanted %v, got %v", c.found, ok)
+			}
+
+			if ok && !out.Equal(c.next) {
+				t.Errorf("unexpected time output: wanted %v, got %v", c.next, out)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next_SplitExclusions(t *testing.T) {
+	var sched = MustParseSchedule("*-*-* *:*:* UTC\n!2020-*-* *:*:00..29 UTC\n!2020-*-* *:*:30..59 UTC")
+
+	// The exclusions together cover whole days, which must be skipped at
+	// once rather than second by second.
+	var start = time.Now()
+	out, ok := sched.Next(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unexpected duration: got %v", elapsed)
+	}
+
+	if want := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC); !ok || !out.Equal(want) {
+		t.Errorf("unexpected time output: wanted %v, got %v, %v", want, out, ok)
+	}
+}
+
+func TestSchedule_Next_ExcludedExpression(t *testing.T) {
+	var sched = MustParseSchedule("*-*-* *:*:* UTC\n!*-*-* *:*:* UTC")
+
+	// The exclusion covers the whole expression, whose dates must be
+	// skipped by years rather than days.
+	var start = time.Now()
+	_, ok := sched.Next(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	satisfiable := sched.IsSatisfiable()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("unexpected duration: got %v", elapsed)
+	}
+
+	if ok {
+		t.Errorf("unexpected time found")
+	}
+	if satisfiable {
+		t.Errorf("unexpected satisfiable schedule")
+	}
+}
+
+func TestSchedule_Prev(t *testing.T) {
+	var current = time.Date(2006, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	type Case struct {
+		name  string
+		sched string
+		prev  time.Time
+		found bool
+	}
+
+	for _, c := range []Case{
+		{name: "latest expression", sched: "*-*-* 12:00 UTC\n*-*-* 14:00 UTC", prev: time.Date(2006, 01, 02, 14, 0, 0, 0, time.UTC), found: true},
+		{name: "skip future expression", sched: "2007-*-* UTC\n*-*-* 12:00 UTC", prev: time.Date(2006, 01, 02, 12, 0, 0, 0, time.UTC), found: true},
+		{name: "no prev date", sched: "2007-*-* UTC", found: false},
+		{name: "excluded day", sched: "*-*-* 12:00 UTC\n!2006-01-02 *:*:* UTC", prev: time.Date(2006, 01, 01, 12, 0, 0, 0, time.UTC), found: true},
+		{name: "excluded time", sched: "*-*-* 12,14:00 UTC\n!*-*-* 14:00 UTC", prev: time.Date(2006, 01, 02, 12, 0, 0, 0, time.UTC), found: true},
+		{name: "split exclusions", sched: "*-*-* 12,14:00 UTC\n!2006-01-02 12:*:* UTC\n!2006-01-02 14:*:* UTC", prev: time.Date(2006, 01, 01, 14, 0, 0, 0, time.UTC), found: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, ok := MustParseSchedule(c.sched).Prev(current)
+			if ok != c.found {
+				t.Fatalf("unexpected found output: wanted %v, got %v", c.found, ok)
+			}
+
+			if ok && !out.Equal(c.prev) {
+				t.Errorf("unexpected time output: wanted %v, got %v", c.prev, out)
+			}
+		})
+	}
+}
+
+func TestSchedule_Occurrences(t *testing.T) {
+	var (
+		from = time.Date(2006, 01, 02, 0, 0, 0, 0, time.UTC)
+		to   = time.Date(2006, 01, 03, 0, 0, 0, 0, time.UTC)
+	)
+
+	out := slices.Collect(MustParseSchedule("*-*-* 00/6:00 UTC\n*-*-* 00/4:00 UTC").Occurrences(from, to))
+	expected := []time.Time{
+		time.Date(2006, 01, 02, 0, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 4, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 6, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 8, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 12, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 16, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 18, 0, 0, 0, time.UTC),
+		time.Date(2006, 01, 02, 20, 0, 0, 0, time.UTC),
+	}
+
+	if !reflect.DeepEqual(expected, out) {
+		t.Errorf("unexpected output: wanted %v, got %v", expected, out)
+	}
+}
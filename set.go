@@ -0,0 +1,341 @@
+package zcalendar
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// A bitset is a set of integers between 0 and 63.
+type bitset uint64
+
+// newBitset creates a bitset from a list of values. Values out of the bitset's
+// bounds are ignored.
+func newBitset(values []int) (b bitset) {
+	for _, v := range values {
+		if v >= 0 && v < 64 {
+			b |= 1 << v
+		}
+	}
+	return b
+}
+
+// Contains returns whether the value is in the set.
+func (b bitset) Contains(v int) bool {
+	return v >= 0 && v < 64 && b&(1<<v) != 0
+}
+
+// Next returns the smallest value of the set that is greater or equal to the
+// current value. If there is none, the smallest value of the set is returned
+// and diff is negative, as the values are considered modulo the maximum value.
+func (b bitset) Next(current int) (next int, diff int, ok bool) {
+	if b == 0 {
+		return
+	}
+
+	var rest = b
+	if current > 0 {
+		rest = b >> min(current, 64) << min(current, 64)
+	}
+	if rest == 0 {
+		rest = b
+	}
+
+	next = bits.TrailingZeros64(uint64(rest))
+	return next, next - current, true
+}
+
+// Prev returns the largest value of the set that is lower or equal to the
+// current value. If there is none, the largest value of the set is returned
+// and diff is positive, as the values are considered modulo the maximum value.
+func (b bitset) Prev(current int) (prev int, diff int, ok bool) {
+	if b == 0 {
+		return
+	}
+
+	var rest bitset
+	if current >= 0 {
+		rest = b << (63 - min(current, 63)) >> (63 - min(current, 63))
+	}
+	if rest == 0 {
+		rest = b
+	}
+
+	prev = bits.Len64(uint64(rest)) - 1
+	return prev, prev - current, true
+}
+
+// A yearset is a set of years, starting from a base year.
+type yearset struct {
+	base  int
+	words [4]bitset
+}
+
+// newYearset creates a yearset starting at base from a list of years. Years out
+// of the yearset's bounds are ignored.
+func newYearset(base int, years []int) (y yearset) {
+	y.base = base
+	for _, v := range years {
+		v -= base
+		if v >= 0 && v < 64*len(y.words) {
+			y.words[v/64] |= 1 << (v % 64)
+		}
+	}
+	return y
+}
+
+// Contains returns whether the year is in the set.
+func (y yearset) Contains(year int) bool {
+	v := year - y.base
+	return v >= 0 && v < 64*len(y.words) && y.words[v/64].Contains(v%64)
+}
+
+// Next returns the smallest year of the set that is greater or equal to the
+// current year. Unlike bitset.Next, the years aren't considered modulo
+// anything, so ok is false if there is none.
+func (y yearset) Next(current int) (next int, diff int, ok bool) {
+	v := max(current-y.base, 0)
+	for i := v / 64; i < len(y.words); i++ {
+		var rest = y.words[i]
+		if i == v/64 {
+			rest = rest >> (v % 64) << (v % 64)
+		}
+		if rest != 0 {
+			next = y.base + i*64 + bits.TrailingZeros64(uint64(rest))
+			return next, next - current, true
+		}
+	}
+	return
+}
+
+// Prev returns the largest year of the set that is lower or equal to the
+// current year. Unlike bitset.Prev, the years aren't considered modulo
+// anything, so ok is false if there is none.
+func (y yearset) Prev(current int) (prev int, diff int, ok bool) {
+	v := min(current-y.base, 64*len(y.words)-1)
+	for i := v / 64; i >= 0 && v >= 0; i-- {
+		var rest = y.words[i]
+		if i == v/64 {
+			rest = rest << (63 - v%64) >> (63 - v%64)
+		}
+		if rest != 0 {
+			prev = y.base + i*64 + bits.Len64(uint64(rest)) - 1
+			return prev, prev - current, true
+		}
+	}
+	return
+}
+
+// sets holds the values of an expression's components as sets, so they don't
+// have to be computed on each call to Next, Prev or Matches.
+type sets struct {
+	compiled bool
+
+	// The occurrences of each weekday in the month, see occurrenceBit.
+	occurrences [8]uint32
+
+	years  yearset
+	months bitset
+
+	// The days are indexed by the number of days in the month minus 28,
+	// and by the weekday of its first day minus 1, as the days moved to
+	// the nearest weekday depend on it.
+	days [4][7]bitset
+
+	hours   bitset
+	minutes bitset
+
+	// The seconds are stored as whole seconds, unless there is a
+	// sub-second value in the expression, in which case they are stored as
+	// normalized components in microseconds, as a repeated sub-second
+	// value can have millions of values.
+	seconds    bitset
+	subseconds components
+
+	// The ISO weeks, and the days of the year indexed by their number
+	// divided by 64.
+	weeks    bitset
+	yeardays [6]bitset
+}
+
+// compile computes the sets of values for the expression.
+func (e Expression) compile() (s sets) {
+	s.compiled = true
+
+	s.occurrences = e.weekdays.occurrences()
+	s.years = newYearset(MinYears, e.years.Values(MaxYears))
+	s.months = newBitset(e.months.Values(12))
+	for i := range s.days {
+		plain := newBitset(e.days.Values(28 + i))
+		for first := range s.days[i] {
+			s.days[i][first] = plain | newBitset(e.days.nearestDays(28+i, first+1))
+		}
+	}
+	s.hours = newBitset(e.hours.Values(23))
+	s.minutes = newBitset(e.minutes.Values(59))
+	s.weeks = newBitset(optional(e.weeks, allWeeks).Values(53))
+	for _, v := range optional(e.yeardays, allYeardays).Values(366) {
+		s.yeardays[v/64] |= 1 << (v % 64)
+	}
+
+	if e.seconds.hasFraction(maxSeconds, microsecondsPerSecond) {
+		s.subseconds = e.seconds.normalized(allSeconds, maxSeconds, microsecondsPerSecond)
+		return s
+	}
+	for _, v := range e.seconds.scaledValues(maxSeconds, microsecondsPerSecond) {
+		s.seconds |= 1 << (v / microsecondsPerSecond)
+	}
+
+	return s
+}
+
+// daysOf returns the days of the month matched by the sets.
+func (s *sets) daysOf(year, month int) bitset {
+	return s.days[daysIn(year, month)-28][weekdayOf(year, month, 1)-1]
+}
+
+// containsDate returns whether the date is in the sets.
+func (s *sets) containsDate(year, month, day int) bool {
+	return s.years.Contains(year) &&
+		s.months.Contains(month) &&
+		s.daysOf(year, month).Contains(day) &&
+		s.containsDay(year, month, day, daysIn(year, month))
+}
+
+// containsWeekday returns whether the day of a month with the given number of
+// days matches the weekdays.
+func (s *sets) containsWeekday(weekday, day, days int) bool {
+	return s.occurrences[weekday]&occurrenceBit(day, days) != 0
+}
+
+// containsDay returns whether the day of a month with the given number of days
+// matches the weekdays, the ISO weeks and the days of the year.
+func (s *sets) containsDay(year, month, day, days int) bool {
+	var (
+		date    = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		weekday = (int(date.Weekday())+6)%7 + 1
+		_, week = date.ISOWeek()
+		yearday = date.YearDay()
+	)
+
+	return s.containsWeekday(weekday, day, days) &&
+		s.weeks.Contains(week) &&
+		s.yeardays[yearday/64].Contains(yearday%64)
+}
+
+// equal returns whether both sets have the same values.
+func (s *sets) equal(other sets) bool {
+	return s.occurrences == other.occurrences &&
+		s.years == other.years &&
+		s.months == other.months &&
+		s.days == other.days &&
+		s.hours == other.hours &&
+		s.minutes == other.minutes &&
+		s.seconds == other.seconds &&
+		s.weeks == other.weeks &&
+		s.yeardays == other.yeardays &&
+		slices.Equal(s.subseconds, other.subseconds)
+}
+
+// write writes the values of the sets to the hash, in a stable way.
+func (s *sets) write(h hash.Hash) {
+	var words []uint64
+	for _, w := range s.occurrences {
+		words = append(words, uint64(w))
+	}
+	words = append(words, uint64(s.years.base))
+	for _, w := range s.years.words {
+		words = append(words, uint64(w))
+	}
+	words = append(words, uint64(s.months))
+	for _, ws := range s.days {
+		for _, w := range ws {
+			words = append(words, uint64(w))
+		}
+	}
+	words = append(words, uint64(s.hours), uint64(s.minutes), uint64(s.seconds))
+	for _, c := range s.subseconds {
+		words = append(words, uint64(c.From), uint64(c.To), uint64(c.Repeat))
+	}
+	words = append(words, uint64(s.weeks))
+	for _, w := range s.yeardays {
+		words = append(words, uint64(w))
+	}
+
+	var buf = make([]byte, 0, 8*len(words))
+	for _, w := range words {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	h.Write(buf)
+}
+
+// containsSecond returns whether the second, in microseconds, is in the set.
+func (s *sets) containsSecond(current int) bool {
+	if s.subseconds != nil {
+		return s.subseconds.scaledContains(current, maxSeconds, microsecondsPerSecond)
+	}
+
+	return current%microsecondsPerSecond == 0 && s.seconds.Contains(current/microsecondsPerSecond)
+}
+
+// containsSeconds returns whether all the seconds of the other sets are in the
+// sets. As the sub-second values are computed one by one, false is returned if
+// the other sets have more than maxListedValues of them.
+func (s *sets) containsSeconds(other *sets) bool {
+	if other.subseconds != nil {
+		if s.subseconds == nil {
+			return false
+		}
+		if slices.Equal(s.subseconds, other.subseconds) {
+			return true
+		}
+
+		var v, ok = other.subseconds.first(0, maxSeconds, microsecondsPerSecond)
+		for i := 0; ok; i++ {
+			if i == maxListedValues || !s.containsSecond(v) {
+				return false
+			}
+			v, ok = other.subseconds.first(v+1, maxSeconds, microsecondsPerSecond)
+		}
+		return true
+	}
+
+	for v := 0; v < 60; v++ {
+		if other.seconds.Contains(v) && !s.containsSecond(v*microsecondsPerSecond) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextSecond is like bitset.Next for the seconds, in microseconds.
+func (s *sets) nextSecond(current int) (next int, diff int, ok bool) {
+	if s.subseconds != nil {
+		return s.subseconds.scaledNext(current, maxSeconds, microsecondsPerSecond)
+	}
+
+	// Round up to the next whole second.
+	next, _, ok = s.seconds.Next((current + microsecondsPerSecond - 1) / microsecondsPerSecond)
+	next *= microsecondsPerSecond
+	return next, next - current, ok
+}
+
+// prevSecond is like bitset.Prev for the seconds, in microseconds.
+func (s *sets) prevSecond(current int) (prev int, diff int, ok bool) {
+	if s.subseconds != nil {
+		return s.subseconds.scaledPrev(current, maxSeconds, microsecondsPerSecond)
+	}
+
+	// Round down to the previous whole second, keeping negative values
+	// negative so they wrap around.
+	var whole = current / microsecondsPerSecond
+	if current < 0 {
+		whole = -1
+	}
+
+	prev, _, ok = s.seconds.Prev(whole)
+	prev *= microsecondsPerSecond
+	return prev, prev - current, ok
+}
@@ -0,0 +1,93 @@
+package zcalendar
+
+import "testing"
+
+func TestBitset_Next(t *testing.T) {
+	type Case struct {
+		name    string
+		set     bitset
+		current int
+		out     int
+		diff    int
+		ok      bool
+	}
+
+	for _, c := range []Case{
+		{name: "same value", set: newBitset([]int{1, 7}), current: 7, out: 7, diff: 0, ok: true},
+		{name: "next value", set: newBitset([]int{1, 9}), current: 7, out: 9, diff: 2, ok: true},
+		{name: "wrapped value", set: newBitset([]int{1, 3}), current: 7, out: 1, diff: -6, ok: true},
+		{name: "out of bounds value", set: newBitset([]int{1, 63}), current: 64, out: 1, diff: -63, ok: true},
+		{name: "negative value", set: newBitset([]int{0}), current: -1, out: 0, diff: 1, ok: true},
+		{name: "empty set", set: newBitset(nil), current: 7, ok: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, diff, ok := c.set.Next(c.current)
+			if ok != c.ok || out != c.out || diff != c.diff {
+				t.Errorf("unexpected output: wanted %v, %v, %v, got %v, %v, %v", c.out, c.diff, c.ok, out, diff, ok)
+			}
+		})
+	}
+}
+
+func TestBitset_Prev(t *testing.T) {
+	type Case struct {
+		name    string
+		set     bitset
+		current int
+		out     int
+		diff    int
+		ok      bool
+	}
+
+	for _, c := range []Case{
+		{name: "same value", set: newBitset([]int{1, 7}), current: 7, out: 7, diff: 0, ok: true},
+		{name: "prev value", set: newBitset([]int{1, 9}), current: 7, out: 1, diff: -6, ok: true},
+		{name: "wrapped value", set: newBitset([]int{8, 9}), current: 7, out: 9, diff: 2, ok: true},
+		{name: "out of bounds value", set: newBitset([]int{1, 63}), current: 64, out: 63, diff: -1, ok: true},
+		{name: "negative value", set: newBitset([]int{0, 5}), current: -1, out: 5, diff: 6, ok: true},
+		{name: "empty set", set: newBitset(nil), current: 7, ok: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, diff, ok := c.set.Prev(c.current)
+			if ok != c.ok || out != c.out || diff != c.diff {
+				t.Errorf("unexpected output: wanted %v, %v, %v, got %v, %v, %v", c.out, c.diff, c.ok, out, diff, ok)
+			}
+		})
+	}
+}
+
+func TestYearset(t *testing.T) {
+	var set = newYearset(1970, []int{1960, 1970, 2006, 2100, 2199, 2300})
+
+	type Case struct {
+		name    string
+		current int
+		next    int
+		prev    int
+		nextOk  bool
+		prevOk  bool
+	}
+
+	for _, c := range []Case{
+		{name: "before base", current: 1960, next: 1970, nextOk: true, prevOk: false},
+		{name: "same year", current: 2006, next: 2006, prev: 2006, nextOk: true, prevOk: true},
+		{name: "across words", current: 2050, next: 2100, prev: 2006, nextOk: true, prevOk: true},
+		{name: "after last", current: 2250, prev: 2199, nextOk: false, prevOk: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			next, _, ok := set.Next(c.current)
+			if ok != c.nextOk || (ok && next != c.next) {
+				t.Errorf("unexpected next: wanted %v, %v, got %v, %v", c.next, c.nextOk, next, ok)
+			}
+
+			prev, _, ok := set.Prev(c.current)
+			if ok != c.prevOk || (ok && prev != c.prev) {
+				t.Errorf("unexpected prev: wanted %v, %v, got %v, %v", c.prev, c.prevOk, prev, ok)
+			}
+		})
+	}
+
+	if set.Contains(1960) || !set.Contains(2199) || set.Contains(2198) {
+		t.Errorf("unexpected content: %v", set)
+	}
+}
@@ -2,20 +2,34 @@ package zcalendar
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
+	"math/bits"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-// A weekdayComponent is a single range of weekdays.
+// A weekdayComponent is a single potentially repeating weekday or range of
+// weekdays, or a single weekday restricted to one of its occurrences in the
+// month. As for the other components, a repeated weekday matches the weekdays
+// from it to Sunday, and a repeated range is shifted by the repetition while it
+// starts before the end of the week.
 type weekdayComponent struct {
-	From int
-	To   int
+	From   int
+	To     int
+	Repeat int
+
+	// Nth is the occurrence of the weekday in the month, from 1 to 5
+	// counting from the start of the month, or from -1 to -5 counting
+	// from its end. Zero means every occurrence.
+	Nth int
 }
 
-// weekdays list the valid values for the weekdays in the calendar spec.
-var weekdaysValues = map[string]int{
+// englishWeekdays list the valid values for the weekdays in the calendar spec.
+var englishWeekdays = map[string]int{
 	"monday":    1,
 	"mon":       1,
 	"tuesday":   2,
@@ -43,43 +57,136 @@ var weekdaysStrings = map[int]string{
 	7: "Sun",
 }
 
+// weekdaysValues holds the names of the weekdays accepted by Parse, which are
+// the English ones and those added by RegisterWeekdays. A stored map is never
+// modified, so it can be read while other names are registered.
+var (
+	weekdaysValues atomic.Pointer[map[string]int]
+	weekdaysLock   sync.Mutex
+)
+
+func init() {
+	weekdaysValues.Store(&englishWeekdays)
+}
+
+// weekdayValue returns the value of the weekday name, regardless of its case.
+func weekdayValue(name string) (v int, ok bool) {
+	v, ok = (*weekdaysValues.Load())[strings.ToLower(name)]
+	return v, ok
+}
+
 // parseweekdayValue create a component from the string representation of a
-// weekday.
+// weekday, with an optional repetition or occurrence.
 func parseWeekdayValue(raw string) (c weekdayComponent, err error) {
-	v, ok := weekdaysValues[strings.ToLower(raw)]
+	name, nth, qualified := strings.Cut(raw, "#")
+	name, repeat, repeated := strings.Cut(name, "/")
+
+	v, ok := weekdayValue(name)
 	if !ok {
-		return c, errors.New("invalid weekday")
+		return c, newParseError(CodeInvalidWeekday, 0, len(name), nil, "invalid weekday")
 	}
 	c.From = v
 
+	if repeated {
+		if qualified {
+			return c, newParseError(CodeInvalidRepeat, len(name)+len("/"), len(repeat), nil, "occurrences can't be repeated")
+		}
+
+		c.Repeat, err = parseRepeat(repeat, 1)
+		if err != nil {
+			return c, wrapParseError(err, len(name)+len("/"), "invalid repeat")
+		}
+	}
+
+	if !qualified {
+		return c, nil
+	}
+
+	// The last occurrence can also be written "L", as in cron.
+	if strings.EqualFold(nth, "L") {
+		c.Nth = -1
+		return c, nil
+	}
+
+	n, err := strconv.Atoi(nth)
+	if err != nil || n == 0 || n < -5 || n > 5 {
+		return c, newParseError(CodeInvalidOccurrence, len(name)+len("#"), len(nth), nil, "invalid occurrence")
+	}
+	c.Nth = n
+
 	return c, nil
 }
 
-// parseweekdayRange create a component from the string representation of range
-// of weekdays.
-func parseWeekdayRange(raw string) (c weekdayComponent, err error) {
+// parseweekdayRange create components from the string representation of range
+// of weekdays with an optional repetition. A range wrapping around the end of
+// the week, such as Fri..Mon, is split in a range to Sunday and a range from
+// Monday, so the components are always contained in a single week. A range with
+// the same bounds is a single weekday.
+//
+// A repeated wrapping range is shifted as the range of the same days numbered
+// after Sunday, e.g. Fri..Mon/2 is Fri..Mon and Sun..Wed, and its weekdays are
+// listed.
+func parseWeekdayRange(raw string) (cs weekdayComponents, err error) {
+	raw, repeat, repeated := strings.Cut(raw, "/")
+
+	var step int
+	if repeated {
+		step, err = parseRepeat(repeat, 1)
+		if err != nil {
+			return cs, wrapParseError(err, len(raw)+len("/"), "invalid repeat")
+		}
+	}
+
 	bounds := strings.Split(raw, "..")
 	if len(bounds) != 2 {
-		return c, errors.New("invalid range")
+		return cs, newParseError(CodeInvalidRange, 0, len(raw), nil, "invalid range")
 	}
 
-	v, ok := weekdaysValues[strings.ToLower(bounds[0])]
+	from, ok := weekdayValue(bounds[0])
 	if !ok {
-		return c, errors.New("invalid weekday")
+		return cs, newParseError(CodeInvalidWeekday, 0, len(bounds[0]), nil, "invalid weekday")
 	}
-	c.From = v
 
-	v, ok = weekdaysValues[strings.ToLower(bounds[1])]
+	to, ok := weekdayValue(bounds[1])
 	if !ok {
-		return c, errors.New("invalid weekday")
+		return cs, newParseError(CodeInvalidWeekday, len(bounds[0])+len(".."), len(bounds[1]), nil, "invalid weekday")
 	}
-	c.To = v
 
-	if c.From >= c.To {
-		return c, errors.New("invalid bounds")
+	switch {
+	case from <= to:
+		return weekdayComponents{weekdayRange(from, to, step)}, nil
+	case step == 0:
+		return weekdayComponents{weekdayRange(from, 7, 0), weekdayRange(1, to, 0)}, nil
 	}
 
-	return c, nil
+	var days [8]bool
+	for start := from; start <= 7; start += step {
+		for v := start; v <= start+to+7-from; v++ {
+			days[(v-1)%7+1] = true
+		}
+	}
+
+	var values []int
+	for day := 1; day <= 7; day++ {
+		if days[day] {
+			values = append(values, day)
+		}
+	}
+	for _, c := range normalizeValues(values, 7, 1, false) {
+		cs = append(cs, weekdayComponent{From: c.From, To: c.To})
+	}
+
+	return cs, nil
+}
+
+// weekdayRange returns the component of the weekdays between from and to
+// repeated every step, which is a single repeated weekday if both bounds are
+// the same.
+func weekdayRange(from, to, step int) weekdayComponent {
+	if from == to {
+		return weekdayComponent{From: from, Repeat: step}
+	}
+	return weekdayComponent{From: from, To: to, Repeat: step}
 }
 
 // MarshalText implements the encoding.TextMarshaler interface.
@@ -92,6 +199,14 @@ func (c weekdayComponent) MarshalText() (text []byte, err error) {
 		fmt.Fprintf(&buf, "..%s", weekdaysStrings[c.To])
 	}
 
+	if c.Repeat != 0 {
+		fmt.Fprintf(&buf, "/%d", c.Repeat)
+	}
+
+	if c.Nth != 0 {
+		fmt.Fprintf(&buf, "#%d", c.Nth)
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -100,24 +215,26 @@ type weekdayComponents []weekdayComponent
 // parseweekdayComponents create a slice of components from a string representing a
 // comma-separated list of weekdays values and ranges.
 func parseWeekdayComponents(raw string) (cs weekdayComponents, err error) {
+	var offset int
 	for index, chunk := range strings.Split(raw, ",") {
 		if strings.Contains(chunk, "..") {
-			c, err := parseWeekdayRange(chunk)
+			r, err := parseWeekdayRange(chunk)
 			if err != nil {
-				return cs, fmt.Errorf(`parsing range %d: %w`, index, err)
+				return cs, wrapParseError(err, offset, "parsing range %d", index)
+			}
+			cs = append(cs, r...)
+		} else {
+			c, err := parseWeekdayValue(chunk)
+			if err != nil {
+				return cs, wrapParseError(err, offset, "parsing value %d", index)
 			}
 			cs = append(cs, c)
-			continue
 		}
 
-		c, err := parseWeekdayValue(chunk)
-		if err != nil {
-			return cs, fmt.Errorf(`parsing value %d: %w`, index, err)
-		}
-		cs = append(cs, c)
+		offset += len(chunk) + len(",")
 	}
 
-	return cs, err
+	return cs, nil
 }
 
 // MarshalText implements the encoding.MarshalText interface for a Component
@@ -136,17 +253,90 @@ func (cs weekdayComponents) String() string {
 	return string(b)
 }
 
-// Values return the list of actual values from the various sub-components.
+// normalized returns the components matching the same days in their
+// canonical form, see weekdaysOf. The components are returned as is if their
+// occurrences can't be represented, which can't happen for parsed components.
+func (cs weekdayComponents) normalized() weekdayComponents {
+	if len(cs) == 0 {
+		return cs
+	}
+
+	normal, ok := weekdaysOf(cs.occurrences())
+	if !ok {
+		return cs
+	}
+	return normal
+}
+
+// weekdaysOf returns the components matching the occurrences of each weekday
+// in the masks in their canonical form: runs of at least 3 consecutive
+// weekdays are written as ranges, progressions of at least 3 of the other
+// weekdays up to Sunday are written as repeats, the other weekdays are listed,
+// and the occurrences of the remaining weekdays are listed with as few
+// components as possible, those counted from the start of the month first. It
+// returns false if the occurrences of a weekday aren't those of a list of
+// components, such as the second occurrence that is also the fourth to last.
+func weekdaysOf(masks [8]uint32) (normal weekdayComponents, ok bool) {
+	var plain []int
+
+	for weekday := 1; weekday <= 7; weekday++ {
+		if masks[weekday] == allOccurrences {
+			plain = append(plain, weekday)
+		}
+	}
+
+	if len(plain) == 7 {
+		return allWeekdays, true
+	}
+
+	for _, c := range normalizeValues(plain, 7, 1, true) {
+		normal = append(normal, weekdayComponent{From: c.From, To: c.To, Repeat: c.Repeat})
+	}
+
+	for weekday := 1; weekday <= 7; weekday++ {
+		if masks[weekday] != allOccurrences {
+			nth, ok := nthComponents(weekday, masks[weekday])
+			if !ok {
+				return nil, false
+			}
+			normal = append(normal, nth...)
+		}
+	}
+
+	sort.SliceStable(normal, func(i, j int) bool {
+		return normal[i].From < normal[j].From
+	})
+
+	return normal, true
+}
+
+// isAll returns whether the components match every day.
+func (cs weekdayComponents) isAll() bool {
+	return cs.occurrences() == [8]uint32{0, allOccurrences, allOccurrences, allOccurrences, allOccurrences, allOccurrences, allOccurrences, allOccurrences}
+}
+
+// hasNth returns whether one of the components is restricted to an occurrence
+// of its weekday.
+func (cs weekdayComponents) hasNth() bool {
+	for _, c := range cs {
+		if c.Nth != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Values return the list of the weekdays matched on each of their occurrences
+// from the various sub-components.
 func (cs weekdayComponents) Values() (values []int) {
 	var seen = make(map[int]struct{})
 
 	for _, c := range cs {
-		if c.To == 0 {
-			seen[c.From] = struct{}{}
-		} else {
-			for v := c.From; v <= c.To && v <= 7; v++ {
-				seen[v] = struct{}{}
-			}
+		if c.Nth != 0 {
+			continue
+		}
+		for _, v := range c.days() {
+			seen[v] = struct{}{}
 		}
 	}
 
@@ -159,11 +349,111 @@ func (cs weekdayComponents) Values() (values []int) {
 	return
 }
 
+// Contains returns whether the weekday is valid for the components on each of
+// its occurrences.
 func (cs weekdayComponents) Contains(day int) (ok bool) {
-	for _, v := range cs.Values() {
-		if v == day {
+	for _, c := range cs {
+		if c.Nth == 0 && slices.Contains(c.days(), day) {
 			return true
 		}
 	}
 	return false
 }
+
+// days returns the weekdays of the component, whatever their occurrences.
+func (c weekdayComponent) days() (days []int) {
+	return components{{From: c.From, To: c.To, Repeat: c.Repeat}}.Values(7)
+}
+
+// The occurrences of a weekday in a month are stored as a mask, with a bit for
+// each pair of the numbers of weeks before and after the day, as they give
+// both its rank from the start and from the end of the month.
+//
+// occurrenceBit returns the bit of a day of a month with the given number of
+// days.
+func occurrenceBit(day, days int) uint32 {
+	return 1 << (((day-1)/7)*5 + (days-day)/7)
+}
+
+// allOccurrences is the mask of all the occurrences of a weekday, in the
+// months of any length.
+var allOccurrences = func() (mask uint32) {
+	for days := 28; days <= 31; days++ {
+		for day := 1; day <= days; day++ {
+			mask |= occurrenceBit(day, days)
+		}
+	}
+	return mask
+}()
+
+// nthOccurrences returns the mask of the nth occurrence of a weekday, counting
+// from the end of the month if n is negative.
+func nthOccurrences(n int) (mask uint32) {
+	for weeks := 0; weeks < 5; weeks++ {
+		if n > 0 {
+			mask |= 1 << ((n-1)*5 + weeks)
+		} else {
+			mask |= 1 << (weeks*5 - n - 1)
+		}
+	}
+	return mask & allOccurrences
+}
+
+// occurrences returns the masks of the occurrences of each weekday matched by
+// the components, indexed by weekday.
+func (cs weekdayComponents) occurrences() (masks [8]uint32) {
+	for _, c := range cs {
+		var mask = allOccurrences
+		if c.Nth != 0 {
+			mask = nthOccurrences(c.Nth)
+		}
+
+		for _, v := range c.days() {
+			masks[v] |= mask
+		}
+	}
+	return masks
+}
+
+// nthComponents returns the fewest components matching exactly the
+// occurrences of the weekday in the mask, or false if there are none.
+func nthComponents(weekday int, mask uint32) (cs weekdayComponents, ok bool) {
+	if mask == allOccurrences {
+		return weekdayComponents{{From: weekday}}, true
+	}
+
+	var candidates []int
+	for _, n := range []int{1, 2, 3, 4, 5, -1, -2, -3, -4, -5} {
+		if nthOccurrences(n)&^mask == 0 {
+			candidates = append(candidates, n)
+		}
+	}
+
+	var best = -1
+	for subset := 0; subset < 1<<len(candidates); subset++ {
+		if best != -1 && bits.OnesCount(uint(subset)) >= bits.OnesCount(uint(best)) {
+			continue
+		}
+
+		var union uint32
+		for i, n := range candidates {
+			if subset&(1<<i) != 0 {
+				union |= nthOccurrences(n)
+			}
+		}
+		if union == mask {
+			best = subset
+		}
+	}
+
+	if best == -1 {
+		return nil, false
+	}
+
+	for i, n := range candidates {
+		if best&(1<<i) != 0 {
+			cs = append(cs, weekdayComponent{From: weekday, Nth: n})
+		}
+	}
+	return cs, true
+}
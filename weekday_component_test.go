@@ -1,6 +1,9 @@
 package zcalendar
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestParseWeekdayValue(t *testing.T) {
 	testParser(t, parseWeekdayValue, []ParserTestCase{
@@ -34,25 +37,119 @@ func TestParseWeekdayValue(t *testing.T) {
 		{name: "valid lowercase weekday 7", in: "sunday", out: weekdayComponent{From: 7}},
 		{name: "invalid weekday 1", in: "Lundi", err: true},
 		{name: "invalid weekday 2", in: "", err: true},
+		{name: "valid occurrence", in: "Tue#2", out: weekdayComponent{From: 2, Nth: 2}},
+		{name: "valid occurrence from end", in: "Fri#-1", out: weekdayComponent{From: 5, Nth: -1}},
+		{name: "valid last occurrence", in: "fri#l", out: weekdayComponent{From: 5, Nth: -1}},
+		{name: "invalid occurrence 1", in: "Tue#0", err: true},
+		{name: "invalid occurrence 2", in: "Tue#6", err: true},
+		{name: "invalid occurrence 3", in: "Tue#", err: true},
+		{name: "valid repeat", in: "Mon/2", out: weekdayComponent{From: 1, Repeat: 2}},
+		{name: "invalid repeat 1", in: "Mon/x", err: true},
+		{name: "invalid repeat 2", in: "Mon/2#1", err: true},
+		{name: "invalid repeat 3", in: "Mon#1/2", err: true},
 	})
 }
 
 func TestParseWeekdayRange(t *testing.T) {
 	testParser(t, parseWeekdayRange, []ParserTestCase{
-		{name: "valid range 1", in: "Mon..Tue", out: weekdayComponent{From: 1, To: 2}},
-		{name: "valid range 2", in: "Monday..Tuesday", out: weekdayComponent{From: 1, To: 2}},
-		{name: "valid range 3", in: "Monday..Fri", out: weekdayComponent{From: 1, To: 5}},
+		{name: "valid range 1", in: "Mon..Tue", out: weekdayComponents{{From: 1, To: 2}}},
+		{name: "valid range 2", in: "Monday..Tuesday", out: weekdayComponents{{From: 1, To: 2}}},
+		{name: "valid range 3", in: "Monday..Fri", out: weekdayComponents{{From: 1, To: 5}}},
+		{name: "single day range", in: "Wed..Wed", out: weekdayComponents{{From: 3}}},
+		{name: "wrapping range 1", in: "Fri..Mon", out: weekdayComponents{{From: 5, To: 7}, {From: 1}}},
+		{name: "wrapping range 2", in: "Sun..Tue", out: weekdayComponents{{From: 7}, {From: 1, To: 2}}},
+		{name: "wrapping range 3", in: "Thu..Wed", out: weekdayComponents{{From: 4, To: 7}, {From: 1, To: 3}}},
+		{name: "repeated range", in: "Mon..Fri/2", out: weekdayComponents{{From: 1, To: 5, Repeat: 2}}},
+		{name: "repeated single day range", in: "Wed..Wed/2", out: weekdayComponents{{From: 3, Repeat: 2}}},
+		{name: "repeated wrapping range 1", in: "Fri..Mon/2", out: weekdayComponents{{From: 1, To: 3}, {From: 5, To: 7}}},
+		{name: "repeated wrapping range 2", in: "Sat..Mon/3", out: weekdayComponents{{From: 1}, {From: 6}, {From: 7}}},
+		{name: "repeated wrapping range 3", in: "Thu..Mon/2", out: weekdayComponents{{From: 1, To: 7}}},
 		{name: "invalid range 1", in: "Mon..Abe", err: true},
 		{name: "invalid range 2", in: "Cjfh..Friday", err: true},
-		{name: "invalid bounds", in: "Wed..Mon", err: true},
+		{name: "invalid range 3", in: "Mon..Wed..Fri", err: true},
+		{name: "invalid repeat", in: "Mon..Fri/x", err: true},
 	})
 }
 
 func TestParseweekdayComponents(t *testing.T) {
 	testParser(t, parseWeekdayComponents, []ParserTestCase{
 		{name: "valid components", in: "Mon,Wed..Thu", out: weekdayComponents{{From: 1}, {From: 3, To: 4}}},
+		{name: "wrapping range", in: "Fri..Mon,Wed", out: weekdayComponents{{From: 5, To: 7}, {From: 1}, {From: 3}}},
+		{name: "valid occurrences", in: "Mon#1,Fri#L", out: weekdayComponents{{From: 1, Nth: 1}, {From: 5, Nth: -1}}},
+		{name: "occurrence of a range", in: "Mon..Fri#1", err: true},
 		{name: "invalid component 1", in: "Lundi,Wed..Thu", err: true},
 		{name: "empty component 1", in: "Mon,Wed..Thu,", err: true},
 		{name: "empty component 2", in: "Mon,,Wed..Thu", err: true},
 	})
 }
+
+func TestWeekdayComponents_Values(t *testing.T) {
+	type Case struct {
+		name  string
+		comps weekdayComponents
+		out   []int
+	}
+
+	for _, c := range []Case{
+		{name: "single weekday", comps: weekdayComponents{{From: 2}}, out: []int{2}},
+		{name: "range", comps: weekdayComponents{{From: 1, To: 3}}, out: []int{1, 2, 3}},
+		{name: "repeat", comps: weekdayComponents{{From: 1, Repeat: 2}}, out: []int{1, 3, 5, 7}},
+		{name: "repeated range", comps: weekdayComponents{{From: 1, To: 2, Repeat: 3}}, out: []int{1, 2, 4, 5, 7}},
+		{name: "no duplicates", comps: weekdayComponents{{From: 1, Repeat: 3}, {From: 4, To: 5}}, out: []int{1, 4, 5, 7}},
+		{name: "occurrence", comps: weekdayComponents{{From: 1, Nth: 2}, {From: 3}}, out: []int{3}},
+		{name: "no component", comps: weekdayComponents{}, out: []int{}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out := c.comps.Values()
+			if !reflect.DeepEqual(c.out, out) {
+				t.Errorf("unexpected output: wanted %v, got %v", c.out, out)
+			}
+		})
+	}
+}
+
+func TestWeekdayComponents_Contains(t *testing.T) {
+	type Case struct {
+		name  string
+		comps weekdayComponents
+		value int
+		out   bool
+	}
+
+	for _, c := range []Case{
+		{name: "single weekday", comps: weekdayComponents{{From: 1}}, value: 1, out: true},
+		{name: "range", comps: weekdayComponents{{From: 1, To: 4}}, value: 5, out: false},
+		{name: "repeat", comps: weekdayComponents{{From: 1, Repeat: 3}}, value: 7, out: true},
+		{name: "repeat skipped", comps: weekdayComponents{{From: 1, Repeat: 3}}, value: 6, out: false},
+		{name: "repeated range", comps: weekdayComponents{{From: 1, To: 2, Repeat: 3}}, value: 3, out: false},
+		{name: "repeated range shifted", comps: weekdayComponents{{From: 1, To: 2, Repeat: 3}}, value: 5, out: true},
+		{name: "occurrence", comps: weekdayComponents{{From: 1, Nth: 1}}, value: 1, out: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if out := c.comps.Contains(c.value); out != c.out {
+				t.Errorf("unexpected output: wanted %v, got %v", c.out, out)
+			}
+		})
+	}
+}
+
+func TestWeekdayComponents_MarshalText(t *testing.T) {
+	type Case struct {
+		name  string
+		comps weekdayComponents
+		out   string
+	}
+
+	for _, c := range []Case{
+		{name: "range", comps: weekdayComponents{{From: 1, To: 5}}, out: "Mon..Fri"},
+		{name: "repeat", comps: weekdayComponents{{From: 1, Repeat: 2}}, out: "Mon/2"},
+		{name: "repeated range", comps: weekdayComponents{{From: 1, To: 5, Repeat: 2}, {From: 6}}, out: "Mon..Fri/2,Sat"},
+		{name: "occurrence", comps: weekdayComponents{{From: 5, Nth: -1}}, out: "Fri#-1"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if out := c.comps.String(); out != c.out {
+				t.Errorf("unexpected output: wanted %q, got %q", c.out, out)
+			}
+		})
+	}
+}